@@ -6,161 +6,488 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
-	"fmt"
-	"github.com/jameskeane/bcrypt"
+	"encoding/json"
+	"errors"
+	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-type Auther interface {
+// Authenticator fills in request.Principal before the route runs, or returns a 401
+// challenge. It's called again with the response afterwards, like a filter.
+type Authenticator interface {
 	Authenticate(request *Request, response *Response) (*Response)
+}
+
+type Auther interface {
+	Authenticator
 	Signin(userdata string, expiryMinutes int64) string
-	Hash(password string) string
-	Match(password string, hash string) bool
+	SigninPrincipal(principal *Principal, expiryMinutes int64) string
+	Signout(ticket string) error
+	RevokeUser(subject string) error
+	SetSessionStore(store SessionStore)
+	SetPasswordHasher(hasher PasswordHasher)
+	Hash(password string) (string, error)
+	// Match also reports whether the hash is outdated and should be replaced with a new Hash
+	Match(password string, hash string) (bool, bool)
+}
+
+type KeyringAuther interface {
+	Auther
+	Reload() error
+	SigninWithRefresh(principal *Principal, accessMinutes int64, refreshMinutes int64) (string, string)
+	Refresh(refreshTicket string, accessMinutes int64) (string, string, error)
+	SetSlidingExpiry(window time.Duration)
+}
+
+// TicketHeader carries a re-issued ticket when sliding expiry is enabled
+const TicketHeader = "X-Auth-Ticket"
+
+// TicketCookie carries the ticket for browser clients that don't send an Authorization header
+const TicketCookie = "ticket"
+
+// ticketVersion prefixes every ticket to identify its wire format:
+// version|key id length|key id|nonce|ciphertext of a JSON ticket
+const ticketVersion byte = 1
+
+type ticket struct {
+	Id string `json:"id"`
+	// Family is shared by every ticket issued from one refresh ticket chain
+	Family    string     `json:"fam,omitempty"`
+	Refresh   bool       `json:"refresh,omitempty"`
+	Principal *Principal `json:"principal"`
+	Issued    time.Time  `json:"iat"`
+	// Expiry is zero for tickets that never expire
+	Expiry time.Time `json:"exp"`
+	// FamilyLifetime is the family's refresh ticket lifetime, zero when it never expires
+	FamilyLifetime time.Duration `json:"flt,omitempty"`
 }
 
 type defaultAuther struct {
-	key []byte
+	provider      KeyProvider
+	keyring       atomic.Value
+	sessions      SessionStore
+	hasher        PasswordHasher
+	slidingWindow time.Duration
+	refreshMutex  sync.Mutex
 }
 
-func NewDefaultAuther(key []byte) Auther {
-	return &defaultAuther{key}
+func NewDefaultAuther(key []byte) KeyringAuther {
+
+	auther, keyringErr := NewKeyringAuther(&Keyring{"default", map[string][]byte{"default": key}})
+
+	if nil != keyringErr {
+		panic(keyringErr.Error())
+	}
+
+	return auther
 }
 
-func (self *defaultAuther) Authenticate(request *Request, response *Response) (*Response) {
+func NewKeyringAuther(provider KeyProvider) (KeyringAuther, error) {
 
-	if nil != response {
-		return nil
+	auther := &defaultAuther{
+		provider: provider,
+		sessions: NewMemorySessionStore(),
+		hasher:   NewBcryptHasher(0),
 	}
 
-	authHeaders, authExists := request.Http.Header["Authorization"]
+	if reloadErr := auther.Reload(); nil != reloadErr {
+		return nil, reloadErr
+	}
+
+	return auther, nil
+}
 
-	if authExists {
+// Reload fetches the keyring from the provider, keeping the current one if it's invalid
+func (self *defaultAuther) Reload() error {
 
-		header := strings.Trim(authHeaders[0], " ")
-		parts := strings.Split(header, " ")
+	keyring, providerErr := self.provider.Keyring()
+
+	if nil != providerErr {
+		return providerErr
+	}
 
-		if 2 == len(parts) {
+	if validateErr := keyring.validate(); nil != validateErr {
+		return validateErr
+	}
 
-			auth, decodeErr := base64.StdEncoding.DecodeString(parts[1])
+	self.keyring.Store(keyring)
 
-			if nil == decodeErr && "basic" == strings.ToLower(parts[0][:5]) {
+	return nil
+}
 
-				authParts := strings.Split(string(auth), ":")
+func (self *defaultAuther) Authenticate(request *Request, response *Response) (*Response) {
 
-				userData := self.decodeTicket(authParts[0])
+	if nil != response {
+		return self.slide(request, response)
+	}
 
-				if "" != userData {
-					request.UserData = userData
+	scheme, credentials := authorization(request)
+	ticket := ""
 
-					return nil
-				}
-			}
+	switch scheme {
+	case "basic":
+		// the ticket is sent as the basic auth username
+		if auth, decodeErr := base64.StdEncoding.DecodeString(credentials); nil == decodeErr {
+			ticket = strings.Split(string(auth), ":")[0]
 		}
+	case "bearer":
+		ticket = credentials
+	case "":
+		if cookie, cookieErr := request.Http.Cookie(TicketCookie); nil == cookieErr {
+			ticket = cookie.Value
+		}
+	}
+
+	if ticket := self.decodeTicket(ticket); nil != ticket && !ticket.Refresh && !self.isRevoked(ticket) {
+
+		request.Principal = ticket.Principal
+		request.UserData = ticket.Principal.Subject
+		request.ticket = ticket
+
+		return nil
 	}
 
 	return &Response{
 		Status: 401,
-		Header: map[string][]string{"Www-Authenticate": []string{"Basic/WebAPI"}},
+		Header: map[string][]string{"Www-Authenticate": []string{`Basic realm="WebAPI"`, "Bearer"}},
 	}
 }
 
 func (self *defaultAuther) Signin(userdata string, expiryMinutes int64) string {
 
-	return self.encodeTicket(userdata, expiryMinutes)
+	return self.SigninPrincipal(&Principal{Subject: userdata}, expiryMinutes)
+}
+
+func (self *defaultAuther) SigninPrincipal(principal *Principal, expiryMinutes int64) string {
+
+	return self.encodeTicket(newTicket(principal, expiryMinutes, "", false))
+}
+
+// SigninWithRefresh issues a short-lived access ticket and a long-lived refresh ticket
+func (self *defaultAuther) SigninWithRefresh(principal *Principal, accessMinutes int64, refreshMinutes int64) (string, string) {
+
+	family := Uuid()
+	access := newTicket(principal, accessMinutes, family, false)
+	refresh := newTicket(principal, refreshMinutes, family, true)
+
+	if !refresh.Expiry.IsZero() {
+		access.FamilyLifetime = refresh.Expiry.Sub(refresh.Issued)
+		refresh.FamilyLifetime = access.FamilyLifetime
+	}
+
+	return self.encodeTicket(access), self.encodeTicket(refresh)
 }
 
-func (self *defaultAuther) Hash(password string) string {
+// Refresh rotates a refresh ticket into a new ticket pair. Presenting a refresh
+// ticket that was already used revokes every ticket in its family.
+func (self *defaultAuther) Refresh(refreshTicket string, accessMinutes int64) (string, string, error) {
 
-	hash, _ := bcrypt.Hash(password)
+	ticket := self.decodeTicket(refreshTicket)
 
-	return hash
+	if nil == ticket || !ticket.Refresh {
+		return "", "", errors.New("Invalid refresh ticket")
+	}
+
+	self.refreshMutex.Lock()
+	defer self.refreshMutex.Unlock()
+
+	if self.isRevoked(ticket) {
+
+		if revokeErr := self.sessions.Revoke(ticket.Family, revocationExpiry(ticket, true)); nil != revokeErr {
+			return "", "", revokeErr
+		}
+
+		return "", "", errors.New("Refresh ticket reused")
+	}
+
+	if revokeErr := self.sessions.Revoke(ticket.Id, revocationExpiry(ticket, false)); nil != revokeErr {
+		return "", "", revokeErr
+	}
+
+	access := newTicket(ticket.Principal, accessMinutes, ticket.Family, false)
+	refreshed := newTicket(ticket.Principal, 0, ticket.Family, true)
+
+	if !ticket.Expiry.IsZero() {
+		refreshed.Expiry = refreshed.Issued.Add(ticket.Expiry.Sub(ticket.Issued))
+	}
+
+	access.FamilyLifetime = ticket.FamilyLifetime
+	refreshed.FamilyLifetime = ticket.FamilyLifetime
+
+	return self.encodeTicket(access), self.encodeTicket(refreshed), nil
 }
 
-func (self *defaultAuther) Match(password string, hash string) bool {
+// SetSlidingExpiry re-issues tickets in the TicketHeader response header once
+// they're within window of expiring; 0 disables it
+func (self *defaultAuther) SetSlidingExpiry(window time.Duration) {
 
-	return bcrypt.Match(password, hash)
+	self.slidingWindow = window
 }
 
-func (self *defaultAuther) encodeTicket(userdata string, expiryMinutes int64) string {
+func (self *defaultAuther) Signout(token string) error {
+
+	ticket := self.decodeTicket(token)
 
-	// generate the token uuid|userdata|expiry
+	if nil == ticket {
+		return errors.New("Invalid ticket")
+	}
 
-	uuid := Uuid()
-	time := time.Now().Add(time.Duration(expiryMinutes) * time.Minute).Format(time.RFC3339)
+	if revokeErr := self.sessions.Revoke(ticket.Id, revocationExpiry(ticket, false)); nil != revokeErr {
+		return revokeErr
+	}
 
-	token := fmt.Sprintf("%s|%s|%s", uuid, userdata, time)
+	// revoke the family too, so its refresh ticket can't start a new session
+	if "" != ticket.Family {
+		return self.sessions.Revoke(ticket.Family, revocationExpiry(ticket, true))
+	}
 
-	// encrypt the token
+	return nil
+}
 
-	block, blockError := aes.NewCipher(self.key)
+func (self *defaultAuther) RevokeUser(subject string) error {
 
-	if nil != blockError {
+	return self.sessions.RevokeUser(subject, time.Now())
+}
+
+func (self *defaultAuther) SetSessionStore(store SessionStore) {
+
+	if nil != store {
+		self.sessions = store
+	}
+}
+
+func (self *defaultAuther) isRevoked(ticket *ticket) bool {
+
+	revoked, revokedErr := self.sessions.IsRevoked(ticket.Id, ticket.Principal.Subject, ticket.Issued)
+
+	if nil == revokedErr && !revoked && "" != ticket.Family {
+		revoked, revokedErr = self.sessions.IsRevoked(ticket.Family, ticket.Principal.Subject, ticket.Issued)
+	}
+
+	return nil != revokedErr || revoked
+}
+
+func (self *defaultAuther) slide(request *Request, response *Response) *Response {
+
+	ticket := request.ticket
+
+	if 0 == self.slidingWindow || nil == ticket || ticket.Expiry.IsZero() || ticket.Issued.IsZero() ||
+		time.Until(ticket.Expiry) > self.slidingWindow {
+
+		return nil
+	}
+
+	slid := newTicket(ticket.Principal, 0, ticket.Family, false)
+	slid.Expiry = slid.Issued.Add(ticket.Expiry.Sub(ticket.Issued))
+	slid.FamilyLifetime = ticket.FamilyLifetime
+
+	if nil == response.Header {
+		response.Header = make(map[string][]string)
+	}
+
+	response.Header[TicketHeader] = []string{self.encodeTicket(slid)}
+
+	if _, cookieErr := request.Http.Cookie(TicketCookie); nil == cookieErr {
+		response.SetCookie(NewTicketCookie(response.Header[TicketHeader][0]))
+	}
+
+	return nil
+}
+
+func (self *defaultAuther) SetPasswordHasher(hasher PasswordHasher) {
+
+	if nil != hasher {
+		self.hasher = hasher
+	}
+}
+
+func (self *defaultAuther) Hash(password string) (string, error) {
+
+	return self.hasher.Hash(password)
+}
+
+func (self *defaultAuther) Match(password string, hash string) (bool, bool) {
+
+	return self.hasher.Match(password, hash)
+}
+
+// NewTicketCookie makes a TicketCookie that scripts can't read and that's only sent over HTTPS
+func NewTicketCookie(ticket string) *http.Cookie {
+
+	return &http.Cookie{
+		Name:     TicketCookie,
+		Value:    ticket,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func newTicket(principal *Principal, expiryMinutes int64, family string, refresh bool) *ticket {
+
+	ticket := &ticket{
+		Id:        Uuid(),
+		Family:    family,
+		Refresh:   refresh,
+		Principal: principal,
+		Issued:    time.Now(),
+	}
+
+	// 0 expiry minutes means the ticket never expires
+	if 0 != expiryMinutes {
+		ticket.Expiry = ticket.Issued.Add(time.Duration(expiryMinutes) * time.Minute)
+	}
+
+	return ticket
+}
+
+// revocationExpiry is how long a revocation must be kept to outlast the ticket,
+// or for a family, every ticket it could still hold. Any refresh ticket in the
+// family was issued before now, so it expires within the family lifetime.
+func revocationExpiry(ticket *ticket, family bool) time.Time {
+
+	if ticket.Expiry.IsZero() || (family && 0 == ticket.FamilyLifetime) {
+		return time.Now().AddDate(100, 0, 0)
+	}
+
+	if family {
+
+		lifetime := ticket.FamilyLifetime
+
+		if ticketLifetime := ticket.Expiry.Sub(ticket.Issued); ticketLifetime > lifetime {
+			lifetime = ticketLifetime
+		}
+
+		return time.Now().Add(lifetime)
+	}
+
+	return ticket.Expiry
+}
+
+func (self *defaultAuther) encodeTicket(ticket *ticket) string {
+
+	token, marshalError := json.Marshal(ticket)
+
+	if nil != marshalError {
 		return ""
 	}
 
-	bytes := []byte(token)
-	encrypted := make([]byte, len(bytes))
+	// seal the token as version|key id length|key id|nonce|ciphertext
 
-	encrypter := cipher.NewCFBEncrypter(block, self.key[:aes.BlockSize])
-	encrypter.XORKeyStream(encrypted, bytes)
+	keyring := self.keyring.Load().(*Keyring)
 
-	return hex.EncodeToString(encrypted)
+	aead, aeadError := newAead(keyring.Keys[keyring.Active])
+
+	if nil != aeadError {
+		return ""
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, nonceError := rand.Read(nonce); nil != nonceError {
+		return ""
+	}
+
+	header := append([]byte{ticketVersion, byte(len(keyring.Active))}, keyring.Active...)
+	prefix := append(append(make([]byte, 0, len(header)+len(nonce)), header...), nonce...)
+	sealed := aead.Seal(prefix, nonce, token, header)
+
+	return hex.EncodeToString(sealed)
 }
 
-func (self *defaultAuther) decodeTicket(token string) string {
+func (self *defaultAuther) decodeTicket(token string) *ticket {
 
 	if "" != token {
 
-		// decrypt the token
+		// open the token
 
 		bytes, decodeError := hex.DecodeString(token)
 
-		if nil != decodeError {
-			return ""
+		if nil != decodeError || 0 == len(bytes) {
+			return nil
 		}
 
-		block, blockError := aes.NewCipher(self.key)
-
-		if nil != blockError {
-			return ""
+		if ticketVersion != bytes[0] || 2 > len(bytes) || 2+int(bytes[1]) > len(bytes) {
+			return nil
 		}
 
-		decrypted := make([]byte, len(bytes))
+		keyring := self.keyring.Load().(*Keyring)
+		headerLength := 2 + int(bytes[1])
+		key, exists := keyring.Keys[string(bytes[2:headerLength])]
 
-		decrypter := cipher.NewCFBDecrypter(block, self.key[:aes.BlockSize])
-		decrypter.XORKeyStream(decrypted, bytes)
-
-		// split the decrypted string into uuid|userdata|expiry
+		if !exists {
+			return nil
+		}
 
-		parts := strings.Split(string(decrypted), "|")
+		decrypted := openTicket(key, bytes[:headerLength], bytes[headerLength:])
 
-		if 3 != len(parts) {
-			return ""
+		if nil == decrypted {
+			return nil
 		}
 
-		// TODO: validate the expiry
-		// TODO: handle 0 (infinite) expiry
-
-		expiry, expiryError := time.Parse(time.RFC3339, parts[2])
+		ticket := &ticket{}
 
-		if nil != expiryError {
-			return ""
+		if unmarshalError := json.Unmarshal(decrypted, ticket); nil != unmarshalError || nil == ticket.Principal {
+			return nil
 		}
 
-		if time.Now().Sub(expiry) > 0 {
-			return ""
+		if !ticket.Expiry.IsZero() && time.Now().After(ticket.Expiry) {
+			return nil
 		}
 
-		// return userdata
-		return parts[1]
+		return ticket
 
 	} else {
 
-		return ""
+		return nil
 	}
 }
 
+func newAead(key []byte) (cipher.AEAD, error) {
+
+	block, blockError := aes.NewCipher(key)
+
+	if nil != blockError {
+		return nil, blockError
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// openTicket decrypts nonce|ciphertext, authenticating the ticket header
+func openTicket(key []byte, header []byte, sealed []byte) []byte {
+
+	aead, aeadError := newAead(key)
+
+	if nil != aeadError || len(sealed) < aead.NonceSize() {
+		return nil
+	}
+
+	decrypted, openError := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], header)
+
+	if nil != openError {
+		return nil
+	}
+
+	return decrypted
+}
+
+// authorization splits the Authorization header into a lowercase scheme and its credentials
+func authorization(request *Request) (string, string) {
+
+	parts := strings.Fields(request.Http.Header.Get("Authorization"))
+
+	if 2 != len(parts) {
+		return "", ""
+	}
+
+	return strings.ToLower(parts[0]), parts[1]
+}
+
 func Uuid() string {
 	// http://stackoverflow.com/questions/15130321
 
@@ -0,0 +1,109 @@
+package gowebapi
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignoutRevokesRefreshFamily(t *testing.T) {
+
+	auther := NewDefaultAuther([]byte("0123456789abcdef"))
+	access, refresh := auther.SigninWithRefresh(&Principal{Subject: "user"}, 5, 60)
+
+	if signoutErr := auther.Signout(access); nil != signoutErr {
+		t.Fatalf("signout failed: %s", signoutErr)
+	}
+
+	if _, _, refreshErr := auther.Refresh(refresh, 5); nil == refreshErr {
+		t.Fatal("refresh ticket still works after signout")
+	}
+}
+
+func TestSignoutRevocationOutlivesAccessTicket(t *testing.T) {
+
+	auther := NewDefaultAuther([]byte("0123456789abcdef")).(*defaultAuther)
+	principal := &Principal{Subject: "user"}
+	family := Uuid()
+
+	// an access ticket that expires long before its family's refresh ticket
+	access := newTicket(principal, 0, family, false)
+	access.Expiry = access.Issued.Add(50 * time.Millisecond)
+	access.FamilyLifetime = time.Hour
+	refresh := newTicket(principal, 60, family, true)
+	refresh.FamilyLifetime = time.Hour
+
+	if signoutErr := auther.Signout(auther.encodeTicket(access)); nil != signoutErr {
+		t.Fatalf("signout failed: %s", signoutErr)
+	}
+
+	// revoking anything else prunes revocations that have expired
+	time.Sleep(100 * time.Millisecond)
+	auther.sessions.Revoke("other", time.Now().Add(time.Hour))
+
+	if _, _, refreshErr := auther.Refresh(auther.encodeTicket(refresh), 5); nil == refreshErr {
+		t.Fatal("refresh ticket works again once the signed out access ticket expired")
+	}
+}
+
+// bearerRequest sends a ticket or JWT in the Authorization header
+func bearerRequest(token string) *Request {
+
+	request := &Request{Http: httptest.NewRequest("GET", "/", nil)}
+	request.Http.Header.Set("Authorization", "Bearer "+token)
+
+	return request
+}
+
+func TestTamperedAndExpiredTicketsRejected(t *testing.T) {
+
+	auther := NewDefaultAuther([]byte("0123456789abcdef"))
+	ticket := auther.SigninPrincipal(&Principal{Subject: "user"}, 5)
+
+	if response := auther.Authenticate(bearerRequest(ticket), nil); nil != response {
+		t.Fatalf("valid ticket rejected with %d", response.Status)
+	}
+
+	// flip a character in the sealed payload
+	last := len(ticket) - 1
+	flipped := "0"
+
+	if "0" == ticket[last:] {
+		flipped = "1"
+	}
+
+	tickets := map[string]string{
+		"tampered": ticket[:last] + flipped,
+		"expired":  auther.SigninPrincipal(&Principal{Subject: "user"}, -1),
+	}
+
+	for name, ticket := range tickets {
+		if response := auther.Authenticate(bearerRequest(ticket), nil); nil == response || 401 != response.Status {
+			t.Errorf("%s ticket wasn't rejected", name)
+		}
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+
+	auther := NewDefaultAuther([]byte("0123456789abcdef"))
+	_, refresh := auther.SigninWithRefresh(&Principal{Subject: "user"}, 5, 60)
+
+	access, rotated, refreshErr := auther.Refresh(refresh, 5)
+
+	if nil != refreshErr {
+		t.Fatalf("refresh failed: %s", refreshErr)
+	}
+
+	if _, _, reuseErr := auther.Refresh(refresh, 5); nil == reuseErr {
+		t.Fatal("used refresh ticket worked again")
+	}
+
+	if response := auther.Authenticate(bearerRequest(access), nil); nil == response {
+		t.Error("access ticket from the reused family still works")
+	}
+
+	if _, _, rotatedErr := auther.Refresh(rotated, 5); nil == rotatedErr {
+		t.Error("rotated refresh ticket from the reused family still works")
+	}
+}
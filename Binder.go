@@ -1,18 +1,53 @@
 package gowebapi
 
 import (
+	"encoding"
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
-	"strings"
-	"encoding/json"
+	"time"
+)
+
+var (
+	defaultValidator    = NewValidator()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
 )
 
 type Binder interface {
 	Bind(*Request) (*Response, error)
 }
 
-type DefaultBinder struct{}
+type ParamError struct {
+	Param   string `json:"param" xml:"param"`
+	Value   string `json:"value" xml:"value"`
+	Message string `json:"message" xml:"message"`
+}
+
+func (self *ParamError) Error() string {
+
+	return fmt.Sprintf("Invalid value %q for param %s: %s", self.Value, self.Param, self.Message)
+}
+
+type DataError struct {
+	Path    string `json:"path" xml:"path"`
+	Message string `json:"message" xml:"message"`
+}
+
+func (self *DataError) Error() string {
+
+	return fmt.Sprintf("Invalid data at %s: %s", self.Path, self.Message)
+}
+
+type DefaultBinder struct {
+	Validator *Validator
+	// Strict rejects unknown fields and mismatched types instead of dropping them,
+	// and JSON bodies with data after the first value.
+	// XML bodies always fail on mismatched types and never on unknown elements,
+	// which encoding/xml can't report.
+	Strict bool
+}
 
 func (self *DefaultBinder) Bind(request *Request) (*Response, error) {
 
@@ -25,6 +60,7 @@ func (self *DefaultBinder) Bind(request *Request) (*Response, error) {
 
 	switch request.Route.Target.(type) {
 	case func(*Request) *Response:
+		self.bindData(request)
 		return request.Route.Target.(func(*Request) *Response)(request), nil
 	default:
 		return self.bindWithReflect(request)
@@ -46,6 +82,34 @@ func (self *DefaultBinder) bindWithReflect(request *Request) (*Response, error)
 
 	args, bindErr := self.bindArgs(target.Type(), request)
 
+	if paramErr, isParamErr := bindErr.(*ParamError); isParamErr {
+		return &Response{
+			Status: 400,
+			Data:   paramErr,
+		}, nil
+	}
+
+	if dataErr, isDataErr := bindErr.(*DataError); isDataErr {
+		return &Response{
+			Status: 400,
+			Data:   dataErr,
+		}, nil
+	}
+
+	if validationErr, isValidationErr := bindErr.(*ValidationError); isValidationErr {
+		return &Response{
+			Status: 422,
+			Data:   validationErr,
+		}, nil
+	}
+
+	if ruleErr, isRuleErr := bindErr.(*RuleError); isRuleErr {
+		return &Response{
+			Status: 500,
+			Data:   ruleErr.Error(),
+		}, nil
+	}
+
 	if nil != bindErr {
 		return nil, errors.New("Binding error: " + bindErr.Error())
 	}
@@ -68,149 +132,311 @@ func (self *DefaultBinder) bindWithReflect(request *Request) (*Response, error)
 func (self *DefaultBinder) bindArgs(targetType reflect.Type, request *Request) ([]reflect.Value, error) {
 
 	args := make([]reflect.Value, 0)
-	var err error
 
 	for argNum := 0; argNum < targetType.NumIn(); argNum++ {
 
 		argType := targetType.In(argNum)
-		var arg reflect.Value
+		paramName := ""
+
+		if argNum < len(request.Route.ParamNames) {
+			paramName = request.Route.ParamNames[argNum]
+		}
+
+		if "*gowebapi.Request" == argType.String() {
+
+			self.bindData(request)
+			args = append(args, reflect.ValueOf(request))
 
-		if reflect.Struct == argType.Kind() ||
-			(reflect.Ptr == argType.Kind() && reflect.Struct == argType.Elem().Kind()) {
+		} else if "*gowebapi.Principal" == argType.String() {
 
-			if "*gowebapi.Request" == argType.String() {
+			args = append(args, reflect.ValueOf(request.Principal))
 
-				args = append(args, reflect.ValueOf(request))
+		} else if "*gowebapi.EventStream" == argType.String() {
 
-			} else {
+			args = append(args, reflect.ValueOf(newEventStream(request)))
 
-				arg, err = self.bindStruct(argType, request.Data)
+		} else if isFileType(argType) {
+
+			args = append(args, self.bindFiles(argType, paramName, request))
+
+		} else if isStructType(argType) || ("" == paramName && isBodyType(argType)) {
+
+			arg, err := self.bindBody(argType, request)
+
+			if nil != err {
+				return nil, err
+			}
+
+			if isStructType(argType) {
+
+				err = self.bindStructValues(arg, request)
 
 				if nil != err {
-					continue
+					return nil, err
 				}
+			}
+
+			err = self.validator().Validate(arg.Interface())
 
-				args = append(args, arg)
+			if nil != err {
+				return nil, err
 			}
+
+			args = append(args, arg)
+
 		} else {
 
-			if argNum < request.Route.Path.NumSubexp() {
+			arg, err := self.bindValues(argType, paramName, self.paramValues(paramName, request))
 
-				paramName := request.Route.Path.SubexpNames()[argNum+1]
-				paramValue := request.Route.Params[paramName]
+			if nil != err {
+				return nil, err
+			}
 
-				arg, err = self.bindParam(argType, paramValue)
+			args = append(args, arg)
+		}
+	}
 
-				if nil != err {
-					continue
-				}
+	return args, nil
+}
 
-				args = append(args, arg)
+func (self *DefaultBinder) validator() *Validator {
 
-			} else {
+	if nil == self.Validator {
+		return defaultValidator
+	}
 
-				args = append(args, reflect.New(argType).Elem())
-			}
+	return self.Validator
+}
+
+func (self *DefaultBinder) bindBody(argType reflect.Type, request *Request) (reflect.Value, error) {
+
+	arg := reflect.New(argType).Elem()
+
+	// struct pointers are always allocated, even without a body
+	if reflect.Ptr == argType.Kind() && isStructType(argType) {
+		arg.Set(reflect.New(argType.Elem()))
+	}
+
+	err := request.Decode(arg.Addr().Interface(), self.Strict)
+
+	return arg, err
+}
+
+// bindData decodes the body into the generic request.Data map for targets that take *Request
+func (self *DefaultBinder) bindData(request *Request) {
+
+	if nil == request.Data {
+		request.Decode(&request.Data, false)
+	}
+}
+
+// bindFiles binds the uploaded files for a field, or any field when unnamed
+func (self *DefaultBinder) bindFiles(argType reflect.Type, paramName string, request *Request) reflect.Value {
+
+	files := make([]*File, 0)
+
+	for _, file := range request.Files {
+		if "" == paramName || paramName == file.Field {
+			files = append(files, file)
 		}
 	}
 
-	return args, err
+	if fileType == argType {
+
+		if 0 == len(files) {
+			return reflect.Zero(fileType)
+		}
+
+		return reflect.ValueOf(files[0])
+	}
+
+	return reflect.ValueOf(files)
 }
 
-func (self *DefaultBinder) bindParam(argType reflect.Type, paramValue string) (reflect.Value, error) {
+// paramValues looks up a named parameter in the path, then the query string, then the form body
+func (self *DefaultBinder) paramValues(paramName string, request *Request) []string {
 
-	param := reflect.New(argType).Elem()
+	if "" == paramName {
+		return nil
+	}
 
-	switch argType.Kind() {
-		case reflect.Bool:
-			value, _ := strconv.ParseBool(paramValue)
-			param.Set(reflect.ValueOf(value))
-		case reflect.Int64:
-			value, _ := strconv.ParseInt(paramValue, 10, 0)
-			param.Set(reflect.ValueOf(value))
-		case reflect.Float64:
-			value, _ := strconv.ParseFloat(paramValue, 0)
-			param.Set(reflect.ValueOf(value))
-		default:
-			param.Set(reflect.ValueOf(paramValue))
+	if paramValue, exists := request.RouteMatch.Params[paramName]; exists {
+		return []string{paramValue}
 	}
 
-	return param, nil
+	if paramValues := request.Http.URL.Query()[paramName]; 0 < len(paramValues) {
+		return paramValues
+	}
+
+	return request.Form[paramName]
 }
 
-func (self *DefaultBinder) bindStruct(structType reflect.Type, data map[string]interface{}) (reflect.Value, error) {
+func (self *DefaultBinder) bindValues(argType reflect.Type, paramName string, paramValues []string) (reflect.Value, error) {
+
+	if reflect.Slice == argType.Kind() && !reflect.PtrTo(argType).Implements(textUnmarshalerType) {
+
+		arg := reflect.MakeSlice(argType, 0, len(paramValues))
+
+		for _, paramValue := range paramValues {
 
-	if reflect.Ptr == structType.Kind() {
-		structType = structType.Elem()
+			element, err := self.bindValues(argType.Elem(), paramName, []string{paramValue})
+
+			if nil != err {
+				return arg, err
+			}
+
+			arg = reflect.Append(arg, element)
+		}
+
+		return arg, nil
 	}
 
-	arg := reflect.New(structType)
+	if 0 == len(paramValues) {
+		return reflect.New(argType).Elem(), nil
+	}
 
-	for fieldNum := 0; fieldNum < arg.Elem().NumField(); fieldNum++ {
+	param, err := self.bindParam(argType, paramValues[0])
 
-		field := arg.Elem().Field(fieldNum)
-		structField := structType.Field(fieldNum)
+	if numErr, isNumErr := err.(*strconv.NumError); isNumErr {
+		err = numErr.Err
+	}
 
-		fieldName := structField.Name
-		// use json tag field name if defined
-		if ("" != structField.Tag.Get("json")) {
-			fieldName = strings.Split(structField.Tag.Get("json"), ",")[0]
+	if nil != err {
+		return param, &ParamError{
+			Param:   paramName,
+			Value:   paramValues[0],
+			Message: err.Error(),
 		}
+	}
+
+	return param, nil
+}
+
+func (self *DefaultBinder) bindParam(argType reflect.Type, paramValue string) (reflect.Value, error) {
+
+	param := reflect.New(argType).Elem()
+
+	if reflect.Ptr == argType.Kind() {
 
-		if nil != data[fieldName] {
-			field.Set(self.bindField(structField.Type, data[fieldName]))
+		value, err := self.bindParam(argType.Elem(), paramValue)
+
+		if nil != err {
+			return param, err
 		}
+
+		param.Set(reflect.New(argType.Elem()))
+		param.Elem().Set(value)
+
+		return param, nil
+	}
+
+	if reflect.PtrTo(argType).Implements(textUnmarshalerType) {
+
+		err := param.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(paramValue))
+
+		return param, err
+	}
+
+	if durationType == argType {
+
+		value, err := time.ParseDuration(paramValue)
+		param.SetInt(int64(value))
+
+		return param, err
+	}
+
+	var err error
+
+	switch argType.Kind() {
+		case reflect.Bool:
+			var value bool
+			value, err = strconv.ParseBool(paramValue)
+			param.SetBool(value)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var value int64
+			value, err = strconv.ParseInt(paramValue, 10, argType.Bits())
+			param.SetInt(value)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			var value uint64
+			value, err = strconv.ParseUint(paramValue, 10, argType.Bits())
+			param.SetUint(value)
+		case reflect.Float32, reflect.Float64:
+			var value float64
+			value, err = strconv.ParseFloat(paramValue, argType.Bits())
+			param.SetFloat(value)
+		case reflect.String:
+			param.SetString(paramValue)
+		default:
+			err = fmt.Errorf("unsupported param type %s", argType)
 	}
 
-	return arg, nil
+	return param, err
 }
 
-func (self *DefaultBinder) bindField(fieldType reflect.Type, data interface{}) (reflect.Value) {
+// bindStructValues fills fields tagged with path:"name", query:"name" or form:"name"
+func (self *DefaultBinder) bindStructValues(arg reflect.Value, request *Request) error {
 
-	dataType := reflect.TypeOf(data)
+	structValue := reflect.Indirect(arg)
+	structType := structValue.Type()
 
-	// TODO: validation goes here
+	query := request.Http.URL.Query()
 
-	if reflect.Map == dataType.Kind() {
+	for fieldNum := 0; fieldNum < structType.NumField(); fieldNum++ {
 
-		fieldValue, _ := self.bindStruct(fieldType, data.(map[string]interface{}))
+		structField := structType.Field(fieldNum)
 
-		return fieldValue
-	
-	} else if reflect.Array == dataType.Kind() || reflect.Slice == dataType.Kind() {
-	
-		arrayType := fieldType.Elem()
-		fieldArray := reflect.MakeSlice(fieldType, 0, 10)
+		var paramName string
+		var paramValues []string
 
-		for _, element := range data.([]interface{}) {
+		if paramName = structField.Tag.Get("path"); "" != paramName && "-" != paramName {
+			if paramValue, exists := request.RouteMatch.Params[paramName]; exists {
+				paramValues = []string{paramValue}
+			}
+		} else if paramName = structField.Tag.Get("query"); "" != paramName && "-" != paramName {
+			paramValues = query[paramName]
+		} else if paramName = structField.Tag.Get("form"); "" != paramName && "-" != paramName {
+
+			if isFileType(structField.Type) {
+				structValue.Field(fieldNum).Set(self.bindFiles(structField.Type, paramName, request))
+				continue
+			}
 
-			fieldValue := self.bindField(arrayType, element)
-			fieldArray = reflect.Append(fieldArray, fieldValue)
+			paramValues = request.Form[paramName]
 		}
 
-		return fieldArray
-	
-	} else if reflect.PtrTo(fieldType).Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()) {
+		if 0 == len(paramValues) {
+			continue
+		}
+
+		fieldValue, err := self.bindValues(structField.Type, paramName, paramValues)
 
-		fieldValue := reflect.New(fieldType)
-		fieldValue.
-			MethodByName("UnmarshalJSON").
-			Call(
-				[]reflect.Value{
-					reflect.ValueOf( []byte("\"" + data.(string) + "\"") ),
-				},
-			)
+		if nil != err {
+			return err
+		}
 
-		return fieldValue.Elem()
+		structValue.Field(fieldNum).Set(fieldValue)
+	}
+
+	return nil
+}
+
+// isBodyType is true for args other than plain scalars, which bind from the body when unnamed
+func isBodyType(argType reflect.Type) bool {
+
+	switch argType.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map, reflect.Interface:
+		return true
+	}
+
+	return false
+}
 
-	} else if dataType.AssignableTo(fieldType) {
-	
-		return reflect.ValueOf(data)
+func isStructType(argType reflect.Type) bool {
 
-	} else if dataType.ConvertibleTo(fieldType) {
-	
-		return reflect.ValueOf(data).Convert(fieldType)
+	if reflect.Ptr == argType.Kind() {
+		argType = argType.Elem()
 	}
 
-	return reflect.New(fieldType)
+	// structs like time.Time that parse from text bind as params
+	return reflect.Struct == argType.Kind() && !reflect.PtrTo(argType).Implements(textUnmarshalerType)
 }
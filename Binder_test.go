@@ -0,0 +1,89 @@
+package gowebapi
+
+import (
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBindParamTypes(t *testing.T) {
+
+	binder := &DefaultBinder{}
+	stamp, _ := time.Parse(time.RFC3339, "2024-02-03T04:05:06Z")
+
+	for _, test := range []struct {
+		value    string
+		expected interface{}
+	}{
+		{"-128", int8(-128)},
+		{"65535", uint16(65535)},
+		{"-9223372036854775808", int64(-9223372036854775808)},
+		{"true", true},
+		{"1.5", float32(1.5)},
+		{"2024-02-03T04:05:06Z", stamp},
+		{"90s", 90 * time.Second},
+		{"10.0.0.1", net.ParseIP("10.0.0.1")},
+	} {
+
+		param, bindErr := binder.bindValues(reflect.TypeOf(test.expected), "param", []string{test.value})
+
+		if nil != bindErr || !reflect.DeepEqual(test.expected, param.Interface()) {
+			t.Errorf("%s: expected %v, got %v (%v)", test.value, test.expected, param.Interface(), bindErr)
+		}
+	}
+
+	for value, expected := range map[string]interface{}{
+		"128":   int8(0),
+		"-1":    uint(0),
+		"70000": uint16(0),
+		"soon":  time.Duration(0),
+		"x":     time.Time{},
+	} {
+		if _, bindErr := binder.bindValues(reflect.TypeOf(expected), "param", []string{value}); nil == bindErr {
+			t.Errorf("%s: bound into %T", value, expected)
+		}
+	}
+}
+
+func TestBindOptionalParams(t *testing.T) {
+
+	binder := &DefaultBinder{}
+
+	missing, _ := binder.bindValues(reflect.TypeOf((*int)(nil)), "param", nil)
+
+	if !missing.IsNil() {
+		t.Errorf("missing optional param bound to %v", missing.Elem())
+	}
+
+	present, _ := binder.bindValues(reflect.TypeOf((*int)(nil)), "param", []string{"0"})
+
+	if present.IsNil() || 0 != present.Elem().Int() {
+		t.Error("optional zero param wasn't bound")
+	}
+}
+
+type paramController struct{}
+
+func (self *paramController) Get(id uint8) *Response {
+
+	return &Response{Status: 200, Data: id}
+}
+
+func TestInvalidParamsAreBadRequests(t *testing.T) {
+
+	handler := NewDefaultHandler()
+	handler.Router().AddRoute("/items/{id}").ToMethod(&paramController{}, "Get")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/items/300", nil))
+
+	paramErr := &ParamError{}
+	json.Unmarshal(recorder.Body.Bytes(), paramErr)
+
+	if 400 != recorder.Code || "id" != paramErr.Param || "300" != paramErr.Value || "" == paramErr.Message {
+		t.Errorf("expected a 400 ParamError for id, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,186 @@
+package gowebapi
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// ApiKeyHeader carries the key checked by the API key Authenticator
+const ApiKeyHeader = "X-API-Key"
+
+// CredentialStore looks up the principals behind usernames and API keys.
+// Unknown credentials return a nil principal and no error.
+type CredentialStore interface {
+	// User also returns the password hash, made by the Authenticator's PasswordHasher
+	User(username string) (*Principal, string, error)
+	ApiKey(key string) (*Principal, error)
+}
+
+type basicAuther struct {
+	realm       string
+	credentials CredentialStore
+	hasher      PasswordHasher
+	dummyHash   string
+}
+
+// NewBasicAuther authenticates username:password Basic credentials, hashing
+// passwords with bcrypt when hasher is nil
+func NewBasicAuther(realm string, credentials CredentialStore, hasher PasswordHasher) Authenticator {
+
+	if nil == hasher {
+		hasher = NewBcryptHasher(0)
+	}
+
+	// unknown users are matched against a dummy hash, so they take as long as known ones
+	dummyHash, _ := hasher.Hash(Uuid())
+
+	return &basicAuther{realm, credentials, hasher, dummyHash}
+}
+
+func (self *basicAuther) Authenticate(request *Request, response *Response) *Response {
+
+	if nil != response {
+		return nil
+	}
+
+	scheme, credentials := authorization(request)
+
+	if "basic" != scheme {
+		return self.challenge()
+	}
+
+	auth, decodeErr := base64.StdEncoding.DecodeString(credentials)
+	separator := strings.Index(string(auth), ":")
+
+	if nil != decodeErr || -1 == separator {
+		return self.challenge()
+	}
+
+	principal, hash, lookupErr := self.credentials.User(string(auth[:separator]))
+
+	if nil != lookupErr {
+		return &Response{Status: 500}
+	}
+
+	if nil == principal {
+		self.hasher.Match(string(auth[separator+1:]), self.dummyHash)
+		return self.challenge()
+	}
+
+	if matched, _ := self.hasher.Match(string(auth[separator+1:]), hash); !matched {
+		return self.challenge()
+	}
+
+	request.Principal = principal
+	request.UserData = principal.Subject
+
+	return nil
+}
+
+func (self *basicAuther) challenge() *Response {
+
+	return &Response{
+		Status: 401,
+		Header: map[string][]string{"Www-Authenticate": []string{`Basic realm="` + self.realm + `", charset="UTF-8"`}},
+	}
+}
+
+type apiKeyAuther struct {
+	realm       string
+	credentials CredentialStore
+}
+
+// NewApiKeyAuther authenticates keys sent in the ApiKeyHeader request header
+func NewApiKeyAuther(realm string, credentials CredentialStore) Authenticator {
+
+	return &apiKeyAuther{realm, credentials}
+}
+
+func (self *apiKeyAuther) Authenticate(request *Request, response *Response) *Response {
+
+	if nil != response {
+		return nil
+	}
+
+	key := request.Http.Header.Get(ApiKeyHeader)
+
+	if "" == key {
+		return self.challenge()
+	}
+
+	principal, lookupErr := self.credentials.ApiKey(key)
+
+	if nil != lookupErr {
+		return &Response{Status: 500}
+	}
+
+	if nil == principal {
+		return self.challenge()
+	}
+
+	request.Principal = principal
+	request.UserData = principal.Subject
+
+	return nil
+}
+
+func (self *apiKeyAuther) challenge() *Response {
+
+	return &Response{
+		Status: 401,
+		Header: map[string][]string{"Www-Authenticate": []string{`ApiKey realm="` + self.realm + `", header="` + ApiKeyHeader + `"`}},
+	}
+}
+
+type chainAuther struct {
+	authenticators []Authenticator
+}
+
+// NewChainAuther accepts a request if any of the authenticators does, trying them
+// in order. When none do, the 401 offers every authenticator's challenge.
+func NewChainAuther(authenticators ...Authenticator) Authenticator {
+
+	return &chainAuther{authenticators}
+}
+
+func (self *chainAuther) Authenticate(request *Request, response *Response) *Response {
+
+	if nil != response {
+
+		// let each authenticator finish up, e.g. to slide the ticket that was used
+		for _, authenticator := range self.authenticators {
+			if responseOverride := authenticator.Authenticate(request, response); nil != responseOverride {
+				return responseOverride
+			}
+		}
+
+		return nil
+	}
+
+	challenges := []string{}
+
+	for _, authenticator := range self.authenticators {
+
+		failure := authenticator.Authenticate(request, nil)
+
+		if nil == failure {
+			return nil
+		}
+
+		// anything but a challenge, like a failed lookup, isn't worth trying the others for
+		if 401 != failure.Status {
+			return failure
+		}
+
+		for _, challenge := range failure.Header["Www-Authenticate"] {
+			if !contains(challenges, challenge) {
+				challenges = append(challenges, challenge)
+			}
+		}
+	}
+
+	return &Response{
+		Status: 401,
+		Header: map[string][]string{"Www-Authenticate": challenges},
+	}
+}
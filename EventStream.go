@@ -0,0 +1,216 @@
+package gowebapi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventStreamFormat is the response format of Server-Sent Events
+const EventStreamFormat = "text/event-stream"
+
+// Event is a Server-Sent Event. Data is formatted with the request's negotiated
+// response formatter and sent as one data line per line.
+type Event struct {
+	Id   string
+	Name string
+	Data interface{}
+	// Retry tells the client how long to wait before reconnecting
+	Retry time.Duration
+}
+
+// EventStream sends Server-Sent Events to a client. Bind it as a target argument
+// and return its Response, then Send events from another goroutine until Done.
+type EventStream struct {
+	// LastEventId is the Last-Event-ID of a client resuming the stream
+	LastEventId string
+	// Heartbeat is how often to send a comment to keep idle connections open, 15s by default
+	Heartbeat time.Duration
+	formatter ResponseFormatter
+	frames    chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var errEventStreamClosed = errors.New("Event stream closed")
+
+func newEventStream(request *Request) *EventStream {
+
+	stream := &EventStream{
+		LastEventId: request.Http.Header.Get("Last-Event-ID"),
+		Heartbeat:   15 * time.Second,
+		formatter:   request.responseFormatter,
+		frames:      make(chan []byte, 16),
+		done:        make(chan struct{}),
+	}
+
+	if nil == stream.formatter {
+		stream.formatter = &JsonFormatter{}
+	}
+
+	// net/http cancels the context when the client disconnects or the request is done
+	go func() {
+		select {
+		case <-request.Http.Context().Done():
+			stream.Close()
+		case <-stream.done:
+		}
+	}()
+
+	return stream
+}
+
+// Response keeps the connection open, writing events until the stream is closed
+func (self *EventStream) Response() *Response {
+
+	return &Response{
+		Status: 200,
+		Format: EventStreamFormat,
+		Header: map[string][]string{
+			"Cache-Control": []string{"no-cache"},
+			// stop proxies like nginx buffering the stream
+			"X-Accel-Buffering": []string{"no"},
+		},
+		Writer: self.write,
+	}
+}
+
+// Send queues an event, failing once the stream is closed
+func (self *EventStream) Send(event *Event) error {
+
+	frame, frameErr := self.frame(event)
+
+	if nil != frameErr {
+		return frameErr
+	}
+
+	select {
+	case <-self.done:
+		return errEventStreamClosed
+	default:
+	}
+
+	select {
+	case self.frames <- frame:
+		return nil
+	case <-self.done:
+		return errEventStreamClosed
+	}
+}
+
+// Done is closed when the client disconnects or the stream is closed
+func (self *EventStream) Done() <-chan struct{} {
+
+	return self.done
+}
+
+// Close ends the stream once the events already sent are written
+func (self *EventStream) Close() {
+
+	self.closeOnce.Do(func() {
+		close(self.done)
+	})
+}
+
+func (self *EventStream) write(writer io.Writer) error {
+
+	defer self.Close()
+
+	// send the headers straight away, so the client knows the stream is open
+	if _, writeErr := io.WriteString(writer, ":\n\n"); nil != writeErr {
+		return writeErr
+	}
+
+	if 0 >= self.Heartbeat {
+		self.Heartbeat = 15 * time.Second
+	}
+
+	heartbeat := time.NewTicker(self.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case frame := <-self.frames:
+			if _, writeErr := writer.Write(frame); nil != writeErr {
+				return writeErr
+			}
+		case <-heartbeat.C:
+			if _, writeErr := io.WriteString(writer, ": heartbeat\n\n"); nil != writeErr {
+				return writeErr
+			}
+		case <-self.done:
+			return self.flush(writer)
+		}
+	}
+}
+
+// flush writes the events sent before the stream was closed
+func (self *EventStream) flush(writer io.Writer) error {
+
+	for {
+		select {
+		case frame := <-self.frames:
+			if _, writeErr := writer.Write(frame); nil != writeErr {
+				return writeErr
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (self *EventStream) frame(event *Event) ([]byte, error) {
+
+	response := &Response{Data: event.Data}
+
+	if formatErr := self.formatter.FormatResponse(response); nil != formatErr {
+		return nil, formatErr
+	}
+
+	frame := &bytes.Buffer{}
+
+	// a newline would end the field early, so ids and names can't have one
+	if "" != event.Id {
+		frame.WriteString("id: " + eventField(event.Id) + "\n")
+	}
+
+	if "" != event.Name {
+		frame.WriteString("event: " + eventField(event.Name) + "\n")
+	}
+
+	if 0 < event.Retry {
+		frame.WriteString("retry: " + strconv.FormatInt(int64(event.Retry/time.Millisecond), 10) + "\n")
+	}
+
+	data := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(string(response.Body))
+
+	for _, line := range strings.Split(data, "\n") {
+		frame.WriteString("data: " + line + "\n")
+	}
+
+	frame.WriteString("\n")
+
+	return frame.Bytes(), nil
+}
+
+func eventField(value string) string {
+
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// acceptsEvents is true when a request's Accept allows an event stream
+func acceptsEvents(header http.Header) bool {
+
+	for _, mediaRange := range parseAccept(header.Get("accept")) {
+		if EventStreamFormat == mediaRange.mimeType && 0 < mediaRange.quality {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,29 @@
+package gowebapi
+
+import (
+	"mime/multipart"
+	"reflect"
+)
+
+// File is a multipart upload, kept in memory or a temporary file until the request is done
+type File struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Size        int64
+	header      *multipart.FileHeader
+}
+
+// Open streams the file's content
+func (self *File) Open() (multipart.File, error) {
+
+	return self.header.Open()
+}
+
+var fileType = reflect.TypeOf((*File)(nil))
+
+// isFileType is true for *File and []*File args, which bind from uploaded files
+func isFileType(argType reflect.Type) bool {
+
+	return fileType == argType || (reflect.Slice == argType.Kind() && fileType == argType.Elem())
+}
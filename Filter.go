@@ -1,7 +1,10 @@
 package gowebapi
 
 import (
+	"crypto/subtle"
 	"log"
+	"net/http"
+	"reflect"
 )
 
 type filterFunc func(*Request, *Response) (*Response)
@@ -21,6 +24,20 @@ func (self *Filter) All() []filterFunc {
 	return self.filters
 }
 
+// Contains reports whether value was added, so marker filters like CsrfExempt can be found
+func (self *Filter) Contains(value filterFunc) bool {
+
+	pointer := reflect.ValueOf(value).Pointer()
+
+	for _, filter := range self.filters {
+		if pointer == reflect.ValueOf(filter).Pointer() {
+			return true
+		}
+	}
+
+	return false
+}
+
 func LogFilter(request *Request, response *Response) (*Response) {
 
 	if nil != response {
@@ -59,3 +76,125 @@ func CorsFilter(request *Request, response *Response) (*Response) {
 
 	return nil
 }
+
+// AuthorizeFilter returns 401 with a Bearer challenge when no one is signed in, and 403
+// when policy rejects the principal
+func AuthorizeFilter(policy func(*Principal, *Request) bool) filterFunc {
+
+	return func(request *Request, response *Response) *Response {
+
+		if nil != response {
+			return nil
+		}
+
+		if nil == request.Principal {
+			return &Response{
+				Status: 401,
+				Header: map[string][]string{"Www-Authenticate": []string{"Bearer"}},
+			}
+		}
+
+		if !policy(request.Principal, request) {
+			return &Response{Status: 403}
+		}
+
+		return nil
+	}
+}
+
+// RolesFilter allows principals with any of the roles
+func RolesFilter(roles ...string) filterFunc {
+
+	return AuthorizeFilter(func(principal *Principal, request *Request) bool {
+
+		for _, role := range roles {
+			if principal.HasRole(role) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// ScopesFilter allows principals with all of the scopes
+func ScopesFilter(scopes ...string) filterFunc {
+
+	return AuthorizeFilter(func(principal *Principal, request *Request) bool {
+
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// CsrfCookie, CsrfHeader and CsrfField carry the tokens checked by CsrfFilter
+const (
+	CsrfCookie = "csrf_token"
+	CsrfHeader = "X-CSRF-Token"
+	CsrfField  = "csrf_token"
+)
+
+// CsrfFilter protects ticket cookie authenticated requests with double-submit
+// tokens. Responses set a CsrfCookie for clients without one, and state-changing
+// requests must echo it in the CsrfHeader or the CsrfField form field.
+func CsrfFilter(request *Request, response *Response) *Response {
+
+	cookie, cookieErr := request.Http.Cookie(CsrfCookie)
+
+	if nil != response {
+
+		if nil != cookieErr || "" == cookie.Value {
+
+			if token := Uuid(); "" != token {
+				// scripts need to read the token to echo it
+				response.SetCookie(&http.Cookie{
+					Name:     CsrfCookie,
+					Value:    token,
+					Path:     "/",
+					Secure:   true,
+					SameSite: http.SameSiteStrictMode,
+				})
+				response.Header[CsrfHeader] = []string{token}
+			}
+		}
+
+		return nil
+	}
+
+	switch request.Http.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return nil
+	}
+
+	if nil != request.RouteMatch && nil != request.Route && request.Filter.Contains(CsrfExempt) {
+		return nil
+	}
+
+	// without a ticket cookie there are no ambient credentials to forge a request with
+	if _, ticketErr := request.Http.Cookie(TicketCookie); nil != ticketErr {
+		return nil
+	}
+
+	token := request.Http.Header.Get(CsrfHeader)
+
+	if "" == token && nil != request.Form {
+		token = request.Form.Get(CsrfField)
+	}
+
+	if nil != cookieErr || "" == cookie.Value || 1 != subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) {
+		return &Response{Status: 403}
+	}
+
+	return nil
+}
+
+// CsrfExempt opts a route out of CsrfFilter when added with Route.WithFilter
+func CsrfExempt(request *Request, response *Response) *Response {
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package gowebapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthorizeChallengesAnonymousRequests(t *testing.T) {
+
+	handler := NewDefaultHandler()
+	handler.Router().AddRoute("/admin").ToFunc(func() *Response {
+		return &Response{Status: 200}
+	}).RequireRoles("admin")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/admin", nil))
+
+	if 401 != recorder.Code || "Bearer" != recorder.Header().Get("Www-Authenticate") {
+		t.Errorf("expected 401 with a Bearer challenge, got %d %q", recorder.Code, recorder.Header().Get("Www-Authenticate"))
+	}
+}
+
+func TestCsrfRejectsCookieRequestsWithoutToken(t *testing.T) {
+
+	handler := NewDefaultHandler()
+	handler.Filter().Add(CsrfFilter)
+	handler.Router().AddRoute("/items").ToFunc(func() *Response {
+		return &Response{Status: 200}
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/items", nil))
+	token := recorder.Header().Get(CsrfHeader)
+
+	if "" == token {
+		t.Fatal("GET didn't issue a csrf token")
+	}
+
+	post := func(header string) int {
+
+		request := httptest.NewRequest("POST", "/items", nil)
+		request.AddCookie(&http.Cookie{Name: TicketCookie, Value: "ticket"})
+		request.AddCookie(&http.Cookie{Name: CsrfCookie, Value: token})
+
+		if "" != header {
+			request.Header.Set(CsrfHeader, header)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder.Code
+	}
+
+	if status := post(""); 403 != status {
+		t.Errorf("expected 403 without the token, got %d", status)
+	}
+
+	if status := post(token); 200 != status {
+		t.Errorf("expected 200 with the token, got %d", status)
+	}
+}
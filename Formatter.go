@@ -1,9 +1,17 @@
 package gowebapi
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type Formatter interface {
@@ -15,12 +23,19 @@ type RequestFormatter interface {
 	FormatRequest(*Request) error
 }
 
+type RequestDecoder interface {
+	DecodeRequest(request *Request, target interface{}, strict bool) error
+}
+
 type ResponseFormatter interface {
 	Formatter
 	FormatResponse(*Response) error
 }
 
-type JsonFormatter struct{}
+type JsonFormatter struct {
+	// UseNumber decodes numbers into interface{} values as json.Number instead of float64
+	UseNumber bool
+}
 
 func (self *JsonFormatter) MimeType() string {
 
@@ -37,15 +52,208 @@ func (self *JsonFormatter) FormatRequest(request *Request) error {
 
 	request.Body = requestBody
 
-	unmarshalErr := json.Unmarshal(request.Body, &request.Data);
+	return nil
+}
+
+func (self *JsonFormatter) DecodeRequest(request *Request, target interface{}, strict bool) error {
 
-	if nil != unmarshalErr {
-		return unmarshalErr
+	decoder := json.NewDecoder(bytes.NewReader(request.Body))
+
+	if self.UseNumber {
+		decoder.UseNumber()
 	}
 
-	return nil
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	decodeErr := decoder.Decode(target)
+
+	// strict bodies hold a single value
+	if nil == decodeErr && strict {
+		if _, tokenErr := decoder.Token(); io.EOF != tokenErr {
+			return &DataError{"", "unexpected data after the body"}
+		}
+	}
+
+	switch decodeErr.(type) {
+	case nil:
+		return nil
+	case *json.UnmarshalTypeError:
+		// permissive decoding skips mismatched values and keeps the rest
+		if !strict {
+			return nil
+		}
+		typeErr := decodeErr.(*json.UnmarshalTypeError)
+		return &DataError{dataPath(reflect.TypeOf(target), typeErr.Field), "cannot bind " + typeErr.Value + " into " + typeErr.Type.String()}
+	}
+
+	if strings.HasPrefix(decodeErr.Error(), "json: unknown field ") {
+
+		field := strings.Trim(strings.TrimPrefix(decodeErr.Error(), "json: unknown field "), "\"")
+
+		// encoding/json only reports the name, so find where it is
+		if path, found := unknownFieldPath(json.NewDecoder(bytes.NewReader(request.Body)), reflect.TypeOf(target), ""); found {
+			field = path
+		}
+
+		return &DataError{field, "unknown field"}
+	}
+
+	return &DataError{"", decodeErr.Error()}
+}
+
+// dataPath turns an encoding/json field path like items.1.qty into the items[1].qty
+// form that validation errors use, indexing slices, arrays and maps with brackets
+func dataPath(targetType reflect.Type, field string) string {
+
+	path := ""
+
+	for _, name := range strings.Split(field, ".") {
+
+		for reflect.Ptr == targetType.Kind() {
+			targetType = targetType.Elem()
+		}
+
+		switch targetType.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			path += "[" + name + "]"
+			targetType = targetType.Elem()
+			continue
+		case reflect.Struct:
+			if fieldType, found := jsonFieldType(targetType, name); found {
+				targetType = fieldType
+			} else {
+				targetType = interfaceType
+			}
+		}
+
+		if "" != path {
+			path += "."
+		}
+
+		path += name
+	}
+
+	return path
+}
+
+// unknownFieldPath walks a JSON value alongside the type it decodes into, and
+// returns the path of the first object key no struct field takes
+func unknownFieldPath(decoder *json.Decoder, valueType reflect.Type, path string) (string, bool) {
+
+	token, tokenErr := decoder.Token()
+
+	if nil != tokenErr {
+		return "", false
+	}
+
+	for reflect.Ptr == valueType.Kind() {
+		valueType = valueType.Elem()
+	}
+
+	switch token {
+	case json.Delim('{'):
+
+		for decoder.More() {
+
+			keyToken, keyErr := decoder.Token()
+			key, isKey := keyToken.(string)
+
+			if nil != keyErr || !isKey {
+				return "", false
+			}
+
+			fieldPath, fieldType := path+"."+key, interfaceType
+
+			switch valueType.Kind() {
+			case reflect.Struct:
+				var found bool
+				if fieldType, found = jsonFieldType(valueType, key); !found {
+					return strings.TrimPrefix(fieldPath, "."), true
+				}
+			case reflect.Map:
+				fieldPath, fieldType = path+"["+key+"]", valueType.Elem()
+			}
+
+			if unknown, isUnknown := unknownFieldPath(decoder, fieldType, fieldPath); isUnknown {
+				return unknown, true
+			}
+		}
+
+		decoder.Token()
+
+	case json.Delim('['):
+
+		elemType := interfaceType
+
+		if reflect.Slice == valueType.Kind() || reflect.Array == valueType.Kind() {
+			elemType = valueType.Elem()
+		}
+
+		for index := 0; decoder.More(); index++ {
+			if unknown, isUnknown := unknownFieldPath(decoder, elemType, path+"["+strconv.Itoa(index)+"]"); isUnknown {
+				return unknown, true
+			}
+		}
+
+		decoder.Token()
+	}
+
+	return "", false
+}
+
+// jsonFieldType finds the type of the struct field json decodes name into,
+// matching names without case like encoding/json does
+func jsonFieldType(structType reflect.Type, name string) (reflect.Type, bool) {
+
+	var folded reflect.Type
+
+	for fieldNum := 0; fieldNum < structType.NumField(); fieldNum++ {
+
+		structField := structType.Field(fieldNum)
+		tag := structField.Tag.Get("json")
+		fieldName := strings.Split(tag, ",")[0]
+
+		if "-" == tag || ("" != structField.PkgPath && !structField.Anonymous) {
+			continue
+		}
+
+		// promoted fields of embedded structs
+		if structField.Anonymous && "" == fieldName {
+
+			embedded := structField.Type
+
+			if reflect.Ptr == embedded.Kind() {
+				embedded = embedded.Elem()
+			}
+
+			if reflect.Struct == embedded.Kind() {
+				if fieldType, found := jsonFieldType(embedded, name); found {
+					return fieldType, true
+				}
+				continue
+			}
+		}
+
+		if "" == fieldName {
+			fieldName = structField.Name
+		}
+
+		if name == fieldName {
+			return structField.Type, true
+		}
+
+		if nil == folded && strings.EqualFold(name, fieldName) {
+			folded = structField.Type
+		}
+	}
+
+	return folded, nil != folded
 }
 
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
 func (self *JsonFormatter) FormatResponse(response *Response) error {
 
 	body, marshalErr := json.Marshal(response.Data)
@@ -59,6 +267,104 @@ func (self *JsonFormatter) FormatResponse(response *Response) error {
 	return nil
 }
 
+// FormFormatter parses application/x-www-form-urlencoded bodies into request.Form
+type FormFormatter struct {
+	// MaxBytes limits the body size, 10MB by default
+	MaxBytes int64
+}
+
+func (self *FormFormatter) MimeType() string {
+
+	return "application/x-www-form-urlencoded"
+}
+
+func (self *FormFormatter) FormatRequest(request *Request) error {
+
+	maxBytes := self.MaxBytes
+
+	if 0 == maxBytes {
+		maxBytes = 10 << 20
+	}
+
+	requestBody, readErr := ioutil.ReadAll(http.MaxBytesReader(nil, request.Http.Body, maxBytes))
+
+	if nil != readErr {
+		return readErr
+	}
+
+	request.Body = requestBody
+
+	form, parseErr := url.ParseQuery(string(request.Body))
+
+	if nil != parseErr {
+		return &DataError{"", parseErr.Error()}
+	}
+
+	request.Form = form
+
+	return nil
+}
+
+// MultipartFormatter parses multipart/form-data bodies into request.Form and
+// request.Files. Files beyond MaxMemory are streamed to temporary files, which
+// net/http removes once the request is done.
+type MultipartFormatter struct {
+	// MaxMemory limits the bytes of files kept in memory, 32MB by default
+	MaxMemory int64
+	// MaxBytes limits the body size, and so the disk used, 100MB by default
+	MaxBytes int64
+}
+
+func (self *MultipartFormatter) MimeType() string {
+
+	return "multipart/form-data"
+}
+
+func (self *MultipartFormatter) FormatRequest(request *Request) error {
+
+	maxMemory := self.MaxMemory
+	maxBytes := self.MaxBytes
+
+	if 0 == maxMemory {
+		maxMemory = 32 << 20
+	}
+
+	if 0 == maxBytes {
+		maxBytes = 100 << 20
+	}
+
+	request.Http.Body = http.MaxBytesReader(nil, request.Http.Body, maxBytes)
+
+	if parseErr := request.Http.ParseMultipartForm(maxMemory); nil != parseErr {
+		return parseErr
+	}
+
+	request.Form = request.Http.MultipartForm.Value
+
+	// order files by field so binding the first one is predictable
+	fields := make([]string, 0, len(request.Http.MultipartForm.File))
+
+	for field := range request.Http.MultipartForm.File {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		for _, header := range request.Http.MultipartForm.File[field] {
+			request.Files = append(request.Files, &File{
+				Field:       field,
+				Filename:    header.Filename,
+				ContentType: header.Header.Get("Content-Type"),
+				Size:        header.Size,
+				header:      header,
+			})
+		}
+	}
+
+	return nil
+}
+
 type TextFormatter struct{}
 
 func (self *TextFormatter) MimeType() string {
@@ -0,0 +1,81 @@
+package gowebapi
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type pathItem struct {
+	Sku string `json:"sku"`
+	Qty int    `json:"qty"`
+}
+
+type pathOrder struct {
+	Items []pathItem          `json:"items"`
+	Tags  map[string]pathItem `json:"tags"`
+}
+
+func TestStrictJsonErrorsUseValidationPaths(t *testing.T) {
+
+	formatter := &JsonFormatter{}
+
+	for body, path := range map[string]string{
+		`{"items":[{"qty":1},{"qty":"x"}]}`: "items[1].qty",
+		`{"tags":{"a":{"qty":"x"}}}`:        "tags[a].qty",
+	} {
+
+		decodeErr := formatter.DecodeRequest(&Request{Body: []byte(body)}, &pathOrder{}, true)
+
+		if dataErr, isDataErr := decodeErr.(*DataError); !isDataErr || path != dataErr.Path {
+			t.Errorf("%s: expected a DataError at %s, got %v", body, path, decodeErr)
+		}
+	}
+}
+
+func TestStrictJsonUnknownFieldsAndTrailingData(t *testing.T) {
+
+	formatter := &JsonFormatter{}
+
+	for body, path := range map[string]string{
+		`{"items":[{"qty":1},{"qtyy":1}]}`:    "items[1].qtyy",
+		`{"tags":{"a":{"SKU":"x","size":1}}}`: "tags[a].size",
+		`{"colour":"red"}`:                    "colour",
+	} {
+
+		decodeErr := formatter.DecodeRequest(&Request{Body: []byte(body)}, &pathOrder{}, true)
+
+		if dataErr, isDataErr := decodeErr.(*DataError); !isDataErr || path != dataErr.Path || "unknown field" != dataErr.Message {
+			t.Errorf("%s: expected an unknown field at %s, got %v", body, path, decodeErr)
+		}
+	}
+
+	for _, body := range []string{`{"items":[]} {"items":[]}`, `{"items":[]}}`} {
+		if nil == formatter.DecodeRequest(&Request{Body: []byte(body)}, &pathOrder{}, true) {
+			t.Errorf("%s: trailing data accepted", body)
+		}
+	}
+
+	if decodeErr := formatter.DecodeRequest(&Request{Body: []byte(`{"items":[]} `)}, &pathOrder{}, true); nil != decodeErr {
+		t.Errorf("trailing space rejected: %s", decodeErr)
+	}
+}
+
+func TestMalformedFormBodiesAreBadRequests(t *testing.T) {
+
+	handler := NewDefaultHandler()
+	handler.AddRequestFormatter("application/x-www-form-urlencoded", &FormFormatter{})
+	handler.Router().AddRoute("/form").ToFunc(func(request *Request) *Response {
+		return &Response{Status: 200}
+	})
+
+	request := httptest.NewRequest("POST", "/form", strings.NewReader("a=%zz"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if 400 != recorder.Code || !strings.Contains(recorder.Body.String(), "message") {
+		t.Errorf("expected 400 with a DataError body, got %d %s", recorder.Code, recorder.Body.String())
+	}
+}
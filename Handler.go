@@ -1,7 +1,14 @@
 package gowebapi
 
 import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -9,10 +16,12 @@ type Handler interface {
 	http.Handler
 	Router() Router
 	Filter() *Filter
+	SetBinder(Binder)
 	AddFormatter(string, RequestFormatter, ResponseFormatter)
 	AddRequestFormatter(string, RequestFormatter)
 	AddResponseFormatter(string, ResponseFormatter)
 	ClearFormatters()
+	SetDefaultFormat(string)
 }
 
 type defaultHandler struct {
@@ -21,6 +30,7 @@ type defaultHandler struct {
 	filter             *Filter
 	requestFormatters  map[string]RequestFormatter
 	responseFormatters map[string]ResponseFormatter
+	defaultFormat      string
 }
 
 func NewDefaultHandler() Handler {
@@ -33,6 +43,7 @@ func NewDefaultHandler() Handler {
 		filter:             &Filter{make([]filterFunc, 0, 0)},
 		requestFormatters:  map[string]RequestFormatter{"application/json": jsonFormatter},
 		responseFormatters: map[string]ResponseFormatter{"application/json": jsonFormatter},
+		defaultFormat:      "application/json",
 	}
 }
 
@@ -46,6 +57,13 @@ func (self *defaultHandler) Filter() *Filter {
 	return self.filter
 }
 
+func (self *defaultHandler) SetBinder(binder Binder) {
+
+	if nil != binder {
+		self.binder = binder
+	}
+}
+
 func (self *defaultHandler) AddFormatter(mimeType string, requestFormatter RequestFormatter, responseFormatter ResponseFormatter) {
 
 	self.AddRequestFormatter(mimeType, requestFormatter)
@@ -55,14 +73,14 @@ func (self *defaultHandler) AddFormatter(mimeType string, requestFormatter Reque
 func (self *defaultHandler) AddRequestFormatter(mimeType string, requestFormatter RequestFormatter) {
 
 	if nil != requestFormatter {
-		self.requestFormatters[mimeType] = requestFormatter
+		self.requestFormatters[strings.ToLower(mimeType)] = requestFormatter
 	}
 }
 
 func (self *defaultHandler) AddResponseFormatter(mimeType string, responseFormatter ResponseFormatter) {
 
 	if nil != responseFormatter {
-		self.responseFormatters[mimeType] = responseFormatter
+		self.responseFormatters[strings.ToLower(mimeType)] = responseFormatter
 	}
 }
 
@@ -72,6 +90,13 @@ func (self *defaultHandler) ClearFormatters() {
 	self.responseFormatters = map[string]ResponseFormatter{}
 }
 
+// SetDefaultFormat sets the format used when a request has no Content-Type, or
+// doesn't prefer any format over it with Accept
+func (self *defaultHandler) SetDefaultFormat(mimeType string) {
+
+	self.defaultFormat = strings.ToLower(mimeType)
+}
+
 func (self *defaultHandler) ServeHTTP(responseWriter http.ResponseWriter, httpRequest *http.Request) {
 
 	self.handleResponse(self.handleRequest(httpRequest), responseWriter)
@@ -85,6 +110,13 @@ func (self *defaultHandler) handleRequest(httpRequest *http.Request) *Response {
 
 	responseFormat := self.determineResponseFormat(httpRequest.Header)
 
+	// event stream clients get their event data, and any errors, in the default format
+	if "" == responseFormat && acceptsEvents(httpRequest.Header) {
+		responseFormat = self.determineResponseFormat(http.Header{})
+	}
+
+	request.responseFormatter = self.responseFormatters[responseFormat]
+
 	if "" == responseFormat {
 		return &Response{
 			Status: 406,
@@ -92,7 +124,8 @@ func (self *defaultHandler) handleRequest(httpRequest *http.Request) *Response {
 		}
 	}
 
-	if httpRequest.ContentLength > 0 {
+	// -1 is a body of unknown length, like a chunked upload
+	if 0 != httpRequest.ContentLength {
 
 		requestFormat := self.determineRequestFormat(httpRequest.Header)
 
@@ -105,7 +138,31 @@ func (self *defaultHandler) handleRequest(httpRequest *http.Request) *Response {
 		}
 
 		requestFormatter := self.requestFormatters[requestFormat]
+		request.formatter = requestFormatter
 		formatErr := requestFormatter.FormatRequest(request)
+		var tooLargeErr *http.MaxBytesError
+
+		if errors.As(formatErr, &tooLargeErr) || errors.Is(formatErr, multipart.ErrMessageTooLarge) {
+			return &Response{
+				Status: 413,
+				Format: responseFormat,
+				Data:   "Request too large",
+			}
+		}
+
+		// malformed bodies are the client's fault, reported like binding errors
+		if dataErr, isDataErr := formatErr.(*DataError); isDataErr {
+
+			response := &Response{
+				Status: 400,
+				Format: responseFormat,
+				Data:   dataErr,
+			}
+
+			request.responseFormatter.FormatResponse(response)
+
+			return response
+		}
 
 		if nil != formatErr {
 			return &Response{
@@ -116,8 +173,14 @@ func (self *defaultHandler) handleRequest(httpRequest *http.Request) *Response {
 		}
 	}
 
-	route, routeError := self.router.Route(request)
-	request.Route = route
+	match, routeError := self.router.Route(request)
+
+	// filters on misses still see request.Route, as nil
+	if nil == match {
+		match = &RouteMatch{Params: map[string]string{}}
+	}
+
+	request.RouteMatch = match
 
 	if nil != routeError {
 		response := &Response{
@@ -133,13 +196,19 @@ func (self *defaultHandler) handleRequest(httpRequest *http.Request) *Response {
 		return response
 	}
 
-	for _, filter := range append(self.filter.All(), route.Filter.All()...) {
+	for _, filter := range append(self.filter.All(), match.Filter.All()...) {
 		if responseOverride := filter(request, nil); nil != responseOverride {
 			return responseOverride
 		}
 	}
 
-	response, bindError := self.binder.Bind(request)
+	binder := self.binder
+
+	if nil != match.Binder {
+		binder = match.Binder
+	}
+
+	response, bindError := binder.Bind(request)
 
 	if nil != bindError {
 		return &Response{
@@ -153,26 +222,34 @@ func (self *defaultHandler) handleRequest(httpRequest *http.Request) *Response {
 		response.Format = responseFormat
 	}
 
-	responseFormatter := self.responseFormatters[response.Format]
+	// streamed responses are written as they are, in response.Format
+	if nil == response.Stream && nil == response.Writer {
 
-	if nil == responseFormatter {
-		return &Response{
-			Status: 500,
-			Data: "No response formatters available for specified response.Format",
+		responseFormatter := self.responseFormatters[response.Format]
+
+		if nil == responseFormatter {
+			return &Response{
+				Status: 500,
+				Data: "No response formatters available for specified response.Format",
+			}
 		}
-	}
 
-	formatErr := responseFormatter.FormatResponse(response)
+		formatErr := responseFormatter.FormatResponse(response)
 
-	if nil != formatErr {
-		return &Response{
-			Status: 500,
-			Data: formatErr.Error(),
+		if nil != formatErr {
+			return &Response{
+				Status: 500,
+				Data: formatErr.Error(),
+			}
 		}
 	}
 
-	for _, filter := range append(self.filter.All(), route.Filter.All()...) {
+	for _, filter := range append(self.filter.All(), match.Filter.All()...) {
 		if responseOverride := filter(request, response); nil != responseOverride {
+			// the replaced stream is never written, so nothing else would close it
+			if responseOverride != response {
+				closeStream(response)
+			}
 			return responseOverride
 		}
 	}
@@ -180,6 +257,14 @@ func (self *defaultHandler) handleRequest(httpRequest *http.Request) *Response {
 	return response
 }
 
+// closeStream closes a response's stream if it's an io.Closer
+func closeStream(response *Response) {
+
+	if closer, isCloser := response.Stream.(io.Closer); isCloser {
+		closer.Close()
+	}
+}
+
 func (self *defaultHandler) handleResponse(response *Response, responseWriter http.ResponseWriter) {
 
 	for header, values := range response.Header {
@@ -188,56 +273,214 @@ func (self *defaultHandler) handleResponse(response *Response, responseWriter ht
 		}
 	}
 
+	// the format depends on Accept, so caches must too
+	responseWriter.Header().Add("Vary", "Accept")
 	responseWriter.Header().Set("content-type", response.Format)
+
+	// without a Content-Length, net/http sends streams chunked
+	if length := response.contentLength(); -1 != length && "" == responseWriter.Header().Get("content-length") && bodyAllowed(response.Status) {
+		responseWriter.Header().Set("content-length", strconv.FormatInt(length, 10))
+	}
+
 	responseWriter.WriteHeader(response.Status)
-	responseWriter.Write(response.Body)
+
+	var writeErr error
+
+	switch {
+	case nil != response.Writer:
+		writeErr = response.Writer(&flushWriter{responseWriter})
+	case nil != response.Stream:
+		writeErr = copyFlushing(responseWriter, response.Stream)
+		closeStream(response)
+	default:
+		responseWriter.Write(response.Body)
+	}
+
+	// the status is already sent, so all that's left is to log it
+	if nil != writeErr {
+		log.Printf("Error writing response: %s", writeErr)
+	}
 }
 
-func (self *defaultHandler) determineRequestFormat(header http.Header) string {
+// copyFlushing copies a stream to the client, flushing each read so it arrives as it's produced
+func copyFlushing(responseWriter http.ResponseWriter, stream io.Reader) error {
+
+	buffer := make([]byte, 32*1024)
+	writer := &flushWriter{responseWriter}
+
+	for {
+
+		count, readErr := stream.Read(buffer)
+
+		if 0 < count {
+			if _, writeErr := writer.Write(buffer[:count]); nil != writeErr {
+				return writeErr
+			}
+		}
 
-	var mimeType string
+		if io.EOF == readErr {
+			return nil
+		}
 
-	if contentType := header.Get("content-type"); "" != contentType {
-		parts := strings.Split(contentType, ";")
-		if _, exists := self.requestFormatters[parts[0]]; exists {
-			mimeType = parts[0]
+		if nil != readErr {
+			return readErr
 		}
-	} else {
-		for mimeType, _ = range self.requestFormatters {
-			break // grab the first one
+	}
+}
+
+// flushWriter flushes after every write
+type flushWriter struct {
+	responseWriter http.ResponseWriter
+}
+
+func (self *flushWriter) Write(bytes []byte) (int, error) {
+
+	count, writeErr := self.responseWriter.Write(bytes)
+
+	if flusher, isFlusher := self.responseWriter.(http.Flusher); isFlusher && nil == writeErr {
+		flusher.Flush()
+	}
+
+	return count, writeErr
+}
+
+// bodyAllowed is false for statuses that mustn't have a body, or a Content-Length
+func bodyAllowed(status int) bool {
+
+	return !(100 <= status && 200 > status) && 204 != status && 304 != status
+}
+
+func (self *defaultHandler) determineRequestFormat(header http.Header) string {
+
+	contentType := header.Get("content-type")
+
+	if "" == contentType {
+		if _, exists := self.requestFormatters[self.defaultFormat]; exists {
+			return self.defaultFormat
 		}
+		return ""
+	}
+
+	mimeType, _, parseErr := mime.ParseMediaType(contentType)
+
+	if nil != parseErr {
+		return ""
+	}
+
+	if _, exists := self.requestFormatters[mimeType]; exists {
+		return mimeType
 	}
 
-	return mimeType
+	return ""
 }
 
+// determineResponseFormat negotiates with Accept as in RFC 7231 5.3.2. Each format
+// gets the q-value of the most specific range matching it, and the highest q-value
+// wins, with ties going to the range listed first and then the default format.
 func (self *defaultHandler) determineResponseFormat(header http.Header) string {
 
-	var mimeType string
+	mimeTypes := make([]string, 0, len(self.responseFormatters))
 
-	if accept := header.Get("accept"); "" != accept {
+	for mimeType := range self.responseFormatters {
+		mimeTypes = append(mimeTypes, mimeType)
+	}
 
-		accepts := strings.Split(accept, ",")
+	// try the default first, then the rest in a stable order
+	sort.Slice(mimeTypes, func(i, j int) bool {
+		if (self.defaultFormat == mimeTypes[i]) != (self.defaultFormat == mimeTypes[j]) {
+			return self.defaultFormat == mimeTypes[i]
+		}
+		return mimeTypes[i] < mimeTypes[j]
+	})
 
-		for _, accept = range accepts {
-			accept = strings.Trim(accept, " ")
-			parts := strings.Split(accept, ";")
+	accept := header.Get("accept")
 
-			if _, exists := self.responseFormatters[parts[0]]; exists {
-				mimeType = parts[0]
-				break
+	if "" == strings.TrimSpace(accept) {
+		if 0 < len(mimeTypes) {
+			return mimeTypes[0]
+		}
+		return ""
+	}
+
+	ranges := parseAccept(accept)
+	best := ""
+	bestQuality := 0.0
+	bestIndex := 0
+
+	for _, mimeType := range mimeTypes {
+
+		match := -1
+
+		for index, mediaRange := range ranges {
+			if mediaRange.matches(mimeType) && (-1 == match || mediaRange.specificity > ranges[match].specificity) {
+				match = index
 			}
-			if "*/*" == parts[0] {
-				for mimeType, _ = range self.responseFormatters {
-					break // grab the first one
-				}
+		}
+
+		// q=0 means the format is unacceptable
+		if -1 == match || 0 == ranges[match].quality {
+			continue
+		}
+
+		if ranges[match].quality > bestQuality || (ranges[match].quality == bestQuality && match < bestIndex) {
+			best = mimeType
+			bestQuality = ranges[match].quality
+			bestIndex = match
+		}
+	}
+
+	return best
+}
+
+type mediaRange struct {
+	mimeType    string
+	quality     float64
+	specificity int // 0 for */*, 1 for type/*, 2 for type/subtype
+}
+
+func (self mediaRange) matches(mimeType string) bool {
+
+	switch self.specificity {
+	case 0:
+		return true
+	case 1:
+		return strings.HasPrefix(mimeType, self.mimeType[:len(self.mimeType)-1])
+	default:
+		return mimeType == self.mimeType
+	}
+}
+
+// parseAccept reads the media ranges of an Accept header, skipping malformed ones
+func parseAccept(accept string) []mediaRange {
+
+	ranges := []mediaRange{}
+
+	for _, part := range strings.Split(accept, ",") {
+
+		mimeType, params, parseErr := mime.ParseMediaType(strings.TrimSpace(part))
+
+		if nil != parseErr || !strings.Contains(mimeType, "/") {
+			continue
+		}
+
+		mediaRange := mediaRange{mimeType, 1, 2}
+
+		if q, exists := params["q"]; exists {
+			quality, qualityErr := strconv.ParseFloat(q, 64)
+			if nil != qualityErr || 0 > quality || 1 < quality {
+				continue
 			}
+			mediaRange.quality = quality
 		}
-	} else {
-		for mimeType, _ = range self.responseFormatters {
-			break // grab the first one
+
+		if "*/*" == mimeType {
+			mediaRange.specificity = 0
+		} else if strings.HasSuffix(mimeType, "/*") {
+			mediaRange.specificity = 1
 		}
+
+		ranges = append(ranges, mediaRange)
 	}
 
-	return mimeType
+	return ranges
 }
@@ -0,0 +1,137 @@
+package gowebapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type closingReader struct {
+	*strings.Reader
+	closed bool
+}
+
+func (self *closingReader) Close() error {
+
+	self.closed = true
+
+	return nil
+}
+
+func TestOverriddenStreamsAreClosed(t *testing.T) {
+
+	stream := &closingReader{Reader: strings.NewReader("secret")}
+
+	handler := NewDefaultHandler()
+	handler.Router().AddRoute("/download").ToFunc(func() *Response {
+		return &Response{Status: 200, Format: "text/plain", Stream: stream}
+	})
+	handler.Filter().Add(func(request *Request, response *Response) *Response {
+		if nil != response {
+			return &Response{Status: 403, Format: "text/plain", Data: "forbidden"}
+		}
+		return nil
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/download", nil))
+
+	if 403 != recorder.Code {
+		t.Fatalf("expected the filter's 403, got %d", recorder.Code)
+	}
+
+	if !stream.closed {
+		t.Error("replaced stream wasn't closed")
+	}
+}
+
+func TestFiltersSeeNilRouteOnMisses(t *testing.T) {
+
+	handler := NewDefaultHandler()
+	handler.Filter().Add(func(request *Request, response *Response) *Response {
+		if nil == request.Route {
+			return &Response{Status: 418}
+		}
+		return nil
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/missing", nil))
+
+	if 418 != recorder.Code {
+		t.Errorf("expected the filter's 418, got %d", recorder.Code)
+	}
+}
+
+func negotiatingHandler() *defaultHandler {
+
+	handler := NewDefaultHandler().(*defaultHandler)
+	handler.AddFormatter("application/xml", &XmlFormatter{}, &XmlFormatter{})
+	handler.AddResponseFormatter("text/plain", &TextFormatter{})
+
+	return handler
+}
+
+func TestDetermineResponseFormat(t *testing.T) {
+
+	handler := negotiatingHandler()
+
+	for accept, format := range map[string]string{
+		"":                                  "application/json",
+		"*/*":                               "application/json",
+		"text/*":                            "text/plain",
+		"application/*":                     "application/json",
+		"application/xml, application/json": "application/xml",
+		"application/json;q=0.5, application/xml": "application/xml",
+		"*/*, application/json;q=0":               "application/xml",
+		"application/*;q=0, text/plain;q=0.1":     "text/plain",
+		"text/html, */*;q=0.1":                    "application/json",
+		"image/png":                               "",
+		"application/json;q=0":                    "",
+	} {
+		if negotiated := handler.determineResponseFormat(http.Header{"Accept": []string{accept}}); format != negotiated {
+			t.Errorf("Accept %q: expected %q, got %q", accept, format, negotiated)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Accept", "image/png")
+	handler.ServeHTTP(recorder, request)
+
+	if 406 != recorder.Code {
+		t.Errorf("expected 406 for an unsupported Accept, got %d", recorder.Code)
+	}
+}
+
+func TestDetermineRequestFormat(t *testing.T) {
+
+	handler := negotiatingHandler()
+
+	for contentType, format := range map[string]string{
+		"":                               "application/json",
+		"application/xml; charset=utf-8": "application/xml",
+		"Application/XML":                "application/xml",
+		"text/csv":                       "",
+		"application/":                   "",
+	} {
+
+		header := http.Header{}
+
+		if "" != contentType {
+			header.Set("Content-Type", contentType)
+		}
+
+		if determined := handler.determineRequestFormat(header); format != determined {
+			t.Errorf("Content-Type %q: expected %q, got %q", contentType, format, determined)
+		}
+	}
+
+	// the default is only used when it can be decoded
+	handler.SetDefaultFormat("text/plain")
+
+	if determined := handler.determineRequestFormat(http.Header{}); "" != determined {
+		t.Errorf("expected no format for a default without a request formatter, got %q", determined)
+	}
+}
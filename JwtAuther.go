@@ -0,0 +1,404 @@
+package gowebapi
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+type JwtConfig struct {
+	// Keys verify tokens by their kid header ("" for tokens without one):
+	// []byte for HS256, *rsa.PublicKey for RS256, *ecdsa.PublicKey for ES256
+	Keys map[string]interface{}
+	// SigningKey signs tokens issued by Signin: []byte, *rsa.PrivateKey or *ecdsa.PrivateKey
+	SigningKey   interface{}
+	SigningKeyId string
+	Issuer       string
+	Audience     string
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+	// Sessions tracks revoked tokens by jti, in memory by default
+	Sessions SessionStore
+	// Hasher hashes passwords, with bcrypt by default
+	Hasher PasswordHasher
+}
+
+type jwtAuther struct {
+	config JwtConfig
+}
+
+func NewJwtAuther(config JwtConfig) Auther {
+
+	if nil == config.Sessions {
+		config.Sessions = NewMemorySessionStore()
+	}
+
+	if nil == config.Hasher {
+		config.Hasher = NewBcryptHasher(0)
+	}
+
+	return &jwtAuther{config}
+}
+
+func (self *jwtAuther) Authenticate(request *Request, response *Response) *Response {
+
+	if nil != response {
+		return nil
+	}
+
+	scheme, token := authorization(request)
+
+	if "bearer" != scheme {
+		return &Response{
+			Status: 401,
+			Header: map[string][]string{"Www-Authenticate": []string{"Bearer"}},
+		}
+	}
+
+	claims, verifyErr := self.verify(token)
+
+	if nil == verifyErr {
+		verifyErr = self.checkRevoked(claims)
+	}
+
+	if nil != verifyErr {
+		return &Response{
+			Status: 401,
+			Header: map[string][]string{"Www-Authenticate": []string{`Bearer error="invalid_token", error_description="` + verifyErr.Error() + `"`}},
+		}
+	}
+
+	request.Principal = jwtPrincipal(claims)
+	request.UserData = request.Principal.Subject
+
+	return nil
+}
+
+func (self *jwtAuther) Signin(userdata string, expiryMinutes int64) string {
+
+	return self.SigninPrincipal(&Principal{Subject: userdata}, expiryMinutes)
+}
+
+func (self *jwtAuther) SigninPrincipal(principal *Principal, expiryMinutes int64) string {
+
+	now := time.Now()
+	claims := map[string]interface{}{}
+
+	for name, value := range principal.Claims {
+		claims[name] = value
+	}
+
+	claims["jti"] = Uuid()
+	claims["sub"] = principal.Subject
+	claims["iat"] = now.Unix()
+
+	// 0 expiry minutes means the token never expires, as for tickets
+	if 0 != expiryMinutes {
+		claims["exp"] = now.Add(time.Duration(expiryMinutes) * time.Minute).Unix()
+	}
+
+	if 0 < len(principal.Roles) {
+		claims["roles"] = principal.Roles
+	}
+
+	if 0 < len(principal.Scopes) {
+		claims["scope"] = strings.Join(principal.Scopes, " ")
+	}
+
+	if "" != self.config.Issuer {
+		claims["iss"] = self.config.Issuer
+	}
+
+	if "" != self.config.Audience {
+		claims["aud"] = self.config.Audience
+	}
+
+	token, signErr := self.sign(claims)
+
+	if nil != signErr {
+		return ""
+	}
+
+	return token
+}
+
+func (self *jwtAuther) Signout(token string) error {
+
+	claims, verifyErr := self.verify(token)
+
+	if nil != verifyErr {
+		return verifyErr
+	}
+
+	jti, hasJti := claims["jti"].(string)
+
+	if !hasJti {
+		return errors.New("token has no jti")
+	}
+
+	// tokens without exp never expire, so keep their revocation forever
+	expiry := time.Now().AddDate(100, 0, 0)
+
+	if exp, isNumber := claims["exp"].(float64); isNumber {
+		expiry = time.Unix(int64(exp), 0).Add(self.config.Leeway)
+	}
+
+	return self.config.Sessions.Revoke(jti, expiry)
+}
+
+func (self *jwtAuther) RevokeUser(subject string) error {
+
+	return self.config.Sessions.RevokeUser(subject, time.Now())
+}
+
+func (self *jwtAuther) SetSessionStore(store SessionStore) {
+
+	if nil != store {
+		self.config.Sessions = store
+	}
+}
+
+func (self *jwtAuther) checkRevoked(claims map[string]interface{}) error {
+
+	jti, _ := claims["jti"].(string)
+	sub, _ := claims["sub"].(string)
+	// iat has second precision, so tokens issued in the same second as RevokeUser are revoked too
+	iat, _ := claims["iat"].(float64)
+
+	revoked, revokedErr := self.config.Sessions.IsRevoked(jti, sub, time.Unix(int64(iat), 0))
+
+	if nil != revokedErr {
+		return revokedErr
+	}
+
+	if revoked {
+		return errors.New("token revoked")
+	}
+
+	return nil
+}
+
+func (self *jwtAuther) SetPasswordHasher(hasher PasswordHasher) {
+
+	if nil != hasher {
+		self.config.Hasher = hasher
+	}
+}
+
+func (self *jwtAuther) Hash(password string) (string, error) {
+
+	return self.config.Hasher.Hash(password)
+}
+
+func (self *jwtAuther) Match(password string, hash string) (bool, bool) {
+
+	return self.config.Hasher.Match(password, hash)
+}
+
+func (self *jwtAuther) sign(claims map[string]interface{}) (string, error) {
+
+	header := map[string]string{"typ": "JWT"}
+
+	if "" != self.config.SigningKeyId {
+		header["kid"] = self.config.SigningKeyId
+	}
+
+	switch self.config.SigningKey.(type) {
+	case []byte:
+		header["alg"] = "HS256"
+	case *rsa.PrivateKey:
+		header["alg"] = "RS256"
+	case *ecdsa.PrivateKey:
+		header["alg"] = "ES256"
+	default:
+		return "", errors.New("no signing key")
+	}
+
+	headerJson, _ := json.Marshal(header)
+	claimsJson, marshalErr := json.Marshal(claims)
+
+	if nil != marshalErr {
+		return "", marshalErr
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJson) + "." + base64.RawURLEncoding.EncodeToString(claimsJson)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+
+	switch key := self.config.SigningKey.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var signErr error
+		if signature, signErr = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); nil != signErr {
+			return "", signErr
+		}
+	case *ecdsa.PrivateKey:
+		r, s, signErr := ecdsa.Sign(rand.Reader, key, digest[:])
+		if nil != signErr {
+			return "", signErr
+		}
+		// ES256 signatures are r|s, each left-padded to 32 bytes
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (self *jwtAuther) verify(token string) (map[string]interface{}, error) {
+
+	parts := strings.Split(token, ".")
+
+	if 3 != len(parts) {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	if decodeErr := decodeJwtPart(parts[0], &header); nil != decodeErr {
+		return nil, errors.New("malformed header")
+	}
+
+	signature, signatureErr := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if nil != signatureErr {
+		return nil, errors.New("malformed signature")
+	}
+
+	key, keyExists := self.config.Keys[header.Kid]
+
+	if !keyExists {
+		return nil, errors.New("unknown key")
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	digest := sha256.Sum256([]byte(signingInput))
+	verified := false
+
+	// the key type must match alg, so an RSA public key can't be used as an HMAC secret
+	switch key := key.(type) {
+	case []byte:
+		if "HS256" == header.Alg {
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(signingInput))
+			verified = hmac.Equal(signature, mac.Sum(nil))
+		}
+	case *rsa.PublicKey:
+		if "RS256" == header.Alg {
+			verified = nil == rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+		}
+	case *ecdsa.PublicKey:
+		if "ES256" == header.Alg && elliptic.P256() == key.Curve && 64 == len(signature) {
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			verified = ecdsa.Verify(key, digest[:], r, s)
+		}
+	}
+
+	if !verified {
+		return nil, errors.New("invalid signature")
+	}
+
+	claims := map[string]interface{}{}
+
+	if decodeErr := decodeJwtPart(parts[1], &claims); nil != decodeErr {
+		return nil, errors.New("malformed claims")
+	}
+
+	now := time.Now()
+
+	if exp, isNumber := claims["exp"].(float64); isNumber && now.After(time.Unix(int64(exp), 0).Add(self.config.Leeway)) {
+		return nil, errors.New("token expired")
+	} else if _, exists := claims["exp"]; exists && !isNumber {
+		return nil, errors.New("malformed exp")
+	}
+
+	if nbf, isNumber := claims["nbf"].(float64); isNumber && now.Before(time.Unix(int64(nbf), 0).Add(-self.config.Leeway)) {
+		return nil, errors.New("token not yet valid")
+	} else if _, exists := claims["nbf"]; exists && !isNumber {
+		return nil, errors.New("malformed nbf")
+	}
+
+	if "" != self.config.Issuer && self.config.Issuer != claims["iss"] {
+		return nil, errors.New("invalid issuer")
+	}
+
+	if "" != self.config.Audience && !jwtAudience(claims["aud"], self.config.Audience) {
+		return nil, errors.New("invalid audience")
+	}
+
+	return claims, nil
+}
+
+// jwtPrincipal reads roles from a roles claim and scopes from a space separated scope claim or an scp array
+func jwtPrincipal(claims map[string]interface{}) *Principal {
+
+	principal := &Principal{Claims: claims}
+	principal.Subject, _ = claims["sub"].(string)
+
+	if roles, isArray := claims["roles"].([]interface{}); isArray {
+		for _, role := range roles {
+			if role, isString := role.(string); isString {
+				principal.Roles = append(principal.Roles, role)
+			}
+		}
+	}
+
+	if scope, isString := claims["scope"].(string); isString {
+		principal.Scopes = strings.Fields(scope)
+	} else if scopes, isArray := claims["scp"].([]interface{}); isArray {
+		for _, scope := range scopes {
+			if scope, isString := scope.(string); isString {
+				principal.Scopes = append(principal.Scopes, scope)
+			}
+		}
+	}
+
+	return principal
+}
+
+func decodeJwtPart(part string, target interface{}) error {
+
+	partJson, decodeErr := base64.RawURLEncoding.DecodeString(part)
+
+	if nil != decodeErr {
+		return decodeErr
+	}
+
+	return json.Unmarshal(partJson, target)
+}
+
+// jwtAudience checks an aud claim, which may be a string or an array of strings
+func jwtAudience(aud interface{}, audience string) bool {
+
+	switch aud.(type) {
+	case string:
+		return audience == aud.(string)
+	case []interface{}:
+		for _, value := range aud.([]interface{}) {
+			if audience == value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
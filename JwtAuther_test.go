@@ -0,0 +1,41 @@
+package gowebapi
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+)
+
+func TestJwtWithoutExpiry(t *testing.T) {
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	auther := NewJwtAuther(JwtConfig{Keys: map[string]interface{}{"": key}, SigningKey: key})
+
+	if response := auther.Authenticate(bearerRequest(auther.Signin("user", 0)), nil); nil != response {
+		t.Fatalf("non-expiring token rejected with %d", response.Status)
+	}
+}
+
+func TestJwtAlgMustMatchKeyType(t *testing.T) {
+
+	privateKey, keyErr := rsa.GenerateKey(rand.Reader, 2048)
+
+	if nil != keyErr {
+		t.Fatal(keyErr)
+	}
+
+	auther := NewJwtAuther(JwtConfig{Keys: map[string]interface{}{"": &privateKey.PublicKey}, SigningKey: privateKey})
+
+	if response := auther.Authenticate(bearerRequest(auther.Signin("user", 5)), nil); nil != response {
+		t.Fatalf("RS256 token rejected with %d", response.Status)
+	}
+
+	// an HS256 token keyed with the public key, which anyone can know
+	publicKey, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	forger := NewJwtAuther(JwtConfig{SigningKey: publicKey})
+
+	if response := auther.Authenticate(bearerRequest(forger.Signin("admin", 5)), nil); nil == response || 401 != response.Status {
+		t.Error("HS256 token accepted by an RSA key")
+	}
+}
@@ -0,0 +1,82 @@
+package gowebapi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+)
+
+// Keyring holds the active signing key and any verification-only keys, by key id
+type Keyring struct {
+	Active string
+	Keys   map[string][]byte
+}
+
+type KeyProvider interface {
+	Keyring() (*Keyring, error)
+}
+
+func (self *Keyring) Keyring() (*Keyring, error) {
+
+	return self, nil
+}
+
+func (self *Keyring) validate() error {
+
+	if _, exists := self.Keys[self.Active]; !exists {
+		return errors.New("Invalid keyring (active key " + self.Active + " doesn't exist)")
+	}
+
+	for id, key := range self.Keys {
+
+		if "" == id || 255 < len(id) {
+			return errors.New("Invalid keyring (key ids must be 1-255 bytes)")
+		}
+
+		if 16 != len(key) && 24 != len(key) && 32 != len(key) {
+			return errors.New("Invalid keyring (key " + id + " must be 16, 24 or 32 bytes)")
+		}
+	}
+
+	return nil
+}
+
+// FileKeyProvider reads a keyring from a JSON file like
+// {"active": "2024-02", "keys": {"2024-01": "<hex key>", "2024-02": "<hex key>"}}
+type FileKeyProvider struct {
+	Path string
+}
+
+func (self *FileKeyProvider) Keyring() (*Keyring, error) {
+
+	file, readErr := ioutil.ReadFile(self.Path)
+
+	if nil != readErr {
+		return nil, readErr
+	}
+
+	var keyFile struct {
+		Active string            `json:"active"`
+		Keys   map[string]string `json:"keys"`
+	}
+
+	if unmarshalErr := json.Unmarshal(file, &keyFile); nil != unmarshalErr {
+		return nil, unmarshalErr
+	}
+
+	keyring := &Keyring{keyFile.Active, make(map[string][]byte, len(keyFile.Keys))}
+
+	for id, hexKey := range keyFile.Keys {
+
+		key, decodeErr := hex.DecodeString(hexKey)
+
+		if nil != decodeErr {
+			return nil, errors.New("Invalid keyring (key " + id + " isn't hex encoded)")
+		}
+
+		keyring.Keys[id] = key
+	}
+
+	return keyring, nil
+}
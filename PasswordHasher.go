@@ -0,0 +1,269 @@
+package gowebapi
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+	"strings"
+)
+
+// PasswordHasher hashes passwords into strings that encode their algorithm and
+// parameters. Match reports whether the password matches, and whether the hash
+// was made with other parameters and should be rehashed.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Match(password string, hash string) (bool, bool)
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) PasswordHasher {
+
+	if 0 == cost {
+		cost = bcrypt.DefaultCost
+	}
+
+	return &bcryptHasher{cost}
+}
+
+func (self *bcryptHasher) Hash(password string) (string, error) {
+
+	hash, hashErr := bcrypt.GenerateFromPassword([]byte(password), self.cost)
+
+	return string(hash), hashErr
+}
+
+func (self *bcryptHasher) Match(password string, hash string) (bool, bool) {
+
+	if !strings.HasPrefix(hash, "$2") {
+		return false, false
+	}
+
+	if nil != bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) {
+		return false, false
+	}
+
+	cost, costErr := bcrypt.Cost([]byte(hash))
+
+	return true, nil != costErr || self.cost != cost
+}
+
+// scryptHasher encodes hashes as $scrypt$ln=15,r=8,p=1$salt$key
+type scryptHasher struct {
+	logN, r, p int
+}
+
+func NewScryptHasher(logN int, r int, p int) PasswordHasher {
+
+	if 0 == logN {
+		logN = 15
+	}
+
+	if 0 == r {
+		r = 8
+	}
+
+	if 0 == p {
+		p = 1
+	}
+
+	return &scryptHasher{logN, r, p}
+}
+
+func (self *scryptHasher) Hash(password string) (string, error) {
+
+	salt, saltErr := passwordSalt()
+
+	if nil != saltErr {
+		return "", saltErr
+	}
+
+	key, keyErr := scrypt.Key([]byte(password), salt, 1<<uint(self.logN), self.r, self.p, passwordKeyLength)
+
+	if nil != keyErr {
+		return "", keyErr
+	}
+
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", self.logN, self.r, self.p, encodePasswordPart(salt), encodePasswordPart(key)), nil
+}
+
+func (self *scryptHasher) Match(password string, hash string) (bool, bool) {
+
+	parts := strings.Split(hash, "$")
+
+	if 5 != len(parts) || "scrypt" != parts[1] {
+		return false, false
+	}
+
+	var logN, r, p int
+
+	if _, scanErr := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &logN, &r, &p); nil != scanErr || 0 >= logN || 31 < logN {
+		return false, false
+	}
+
+	salt, key, decoded := decodeSaltAndKey(parts[3], parts[4])
+
+	if !decoded {
+		return false, false
+	}
+
+	candidate, candidateErr := scrypt.Key([]byte(password), salt, 1<<uint(logN), r, p, len(key))
+
+	if nil != candidateErr || 1 != subtle.ConstantTimeCompare(key, candidate) {
+		return false, false
+	}
+
+	return true, self.logN != logN || self.r != r || self.p != p || passwordKeyLength != len(key)
+}
+
+// argon2idHasher encodes hashes as $argon2id$v=19$m=65536,t=3,p=4$salt$key
+type argon2idHasher struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+func NewArgon2idHasher(time uint32, memory uint32, threads uint8) PasswordHasher {
+
+	if 0 == time {
+		time = 3
+	}
+
+	if 0 == memory {
+		memory = 64 * 1024
+	}
+
+	if 0 == threads {
+		threads = 4
+	}
+
+	return &argon2idHasher{time, memory, threads}
+}
+
+func (self *argon2idHasher) Hash(password string) (string, error) {
+
+	salt, saltErr := passwordSalt()
+
+	if nil != saltErr {
+		return "", saltErr
+	}
+
+	key := argon2.IDKey([]byte(password), salt, self.time, self.memory, self.threads, passwordKeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, self.memory, self.time, self.threads, encodePasswordPart(salt), encodePasswordPart(key)), nil
+}
+
+func (self *argon2idHasher) Match(password string, hash string) (bool, bool) {
+
+	parts := strings.Split(hash, "$")
+
+	if 6 != len(parts) || "argon2id" != parts[1] {
+		return false, false
+	}
+
+	var version int
+	var memory, time uint32
+	var threads uint8
+
+	if _, scanErr := fmt.Sscanf(parts[2], "v=%d", &version); nil != scanErr || argon2.Version != version {
+		return false, false
+	}
+
+	if _, scanErr := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); nil != scanErr || 0 == time || 0 == threads {
+		return false, false
+	}
+
+	salt, key, decoded := decodeSaltAndKey(parts[4], parts[5])
+
+	if !decoded {
+		return false, false
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+
+	if 1 != subtle.ConstantTimeCompare(key, candidate) {
+		return false, false
+	}
+
+	return true, self.time != time || self.memory != memory || self.threads != threads || passwordKeyLength != len(key)
+}
+
+// migratingHasher hashes with current but still matches hashes made by the
+// legacy hashers, flagging those for rehashing
+type migratingHasher struct {
+	current PasswordHasher
+	legacy  []PasswordHasher
+}
+
+func NewMigratingHasher(current PasswordHasher, legacy ...PasswordHasher) PasswordHasher {
+
+	return &migratingHasher{current, legacy}
+}
+
+func (self *migratingHasher) Hash(password string) (string, error) {
+
+	return self.current.Hash(password)
+}
+
+func (self *migratingHasher) Match(password string, hash string) (bool, bool) {
+
+	if matched, rehash := self.current.Match(password, hash); matched {
+		return true, rehash
+	}
+
+	for _, hasher := range self.legacy {
+		if matched, _ := hasher.Match(password, hash); matched {
+			return true, true
+		}
+	}
+
+	return false, false
+}
+
+const passwordKeyLength = 32
+
+// shorter salts and keys are from truncated hashes, and an empty key would match anything
+const (
+	minPasswordSaltLength = 8
+	minPasswordKeyLength  = 16
+)
+
+func passwordSalt() ([]byte, error) {
+
+	salt := make([]byte, 16)
+
+	if _, randErr := rand.Read(salt); nil != randErr {
+		return nil, errors.New("Unable to generate salt: " + randErr.Error())
+	}
+
+	return salt, nil
+}
+
+func encodePasswordPart(part []byte) string {
+
+	return base64.RawStdEncoding.EncodeToString(part)
+}
+
+func decodePasswordPart(part string) ([]byte, error) {
+
+	return base64.RawStdEncoding.DecodeString(part)
+}
+
+func decodeSaltAndKey(saltPart string, keyPart string) ([]byte, []byte, bool) {
+
+	salt, saltErr := decodePasswordPart(saltPart)
+	key, keyErr := decodePasswordPart(keyPart)
+
+	if nil != saltErr || nil != keyErr || minPasswordSaltLength > len(salt) || minPasswordKeyLength > len(key) {
+		return nil, nil, false
+	}
+
+	return salt, key, true
+}
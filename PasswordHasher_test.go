@@ -0,0 +1,70 @@
+package gowebapi
+
+import (
+	"testing"
+)
+
+func TestPasswordHashersRoundTrip(t *testing.T) {
+
+	// low costs keep the test fast, and the rehash flag is checked against stronger ones
+	hashers := map[string][2]PasswordHasher{
+		"bcrypt":   {NewBcryptHasher(4), NewBcryptHasher(5)},
+		"scrypt":   {NewScryptHasher(10, 8, 1), NewScryptHasher(11, 8, 1)},
+		"argon2id": {NewArgon2idHasher(1, 1024, 1), NewArgon2idHasher(2, 1024, 1)},
+	}
+
+	for name, pair := range hashers {
+
+		hash, hashErr := pair[0].Hash("correct horse")
+
+		if nil != hashErr {
+			t.Fatalf("%s: hash failed: %s", name, hashErr)
+		}
+
+		if matched, rehash := pair[0].Match("correct horse", hash); !matched || rehash {
+			t.Errorf("%s: expected a match without rehash, got %v %v", name, matched, rehash)
+		}
+
+		if matched, _ := pair[0].Match("wrong horse", hash); matched {
+			t.Errorf("%s: wrong password matched", name)
+		}
+
+		if matched, rehash := pair[1].Match("correct horse", hash); !matched || !rehash {
+			t.Errorf("%s: expected a match flagged for rehash after a cost change, got %v %v", name, matched, rehash)
+		}
+	}
+}
+
+func TestMigratingHasherFlagsLegacyHashes(t *testing.T) {
+
+	legacy := NewBcryptHasher(4)
+	hasher := NewMigratingHasher(NewArgon2idHasher(1, 1024, 1), legacy)
+
+	legacyHash, _ := legacy.Hash("correct horse")
+
+	if matched, rehash := hasher.Match("correct horse", legacyHash); !matched || !rehash {
+		t.Errorf("expected legacy hash to match and need rehash, got %v %v", matched, rehash)
+	}
+
+	currentHash, _ := hasher.Hash("correct horse")
+
+	if matched, rehash := hasher.Match("correct horse", currentHash); !matched || rehash {
+		t.Errorf("expected current hash to match without rehash, got %v %v", matched, rehash)
+	}
+}
+
+func TestTruncatedHashesNeverMatch(t *testing.T) {
+
+	hashers := map[string]PasswordHasher{
+		"$scrypt$ln=10,r=8,p=1$c2FsdHNhbHQ$":             NewScryptHasher(10, 8, 1),
+		"$scrypt$ln=10,r=8,p=1$$a2V5a2V5a2V5a2V5a2V5":    NewScryptHasher(10, 8, 1),
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$":     NewArgon2idHasher(1, 1024, 1),
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5": NewArgon2idHasher(1, 1024, 1),
+	}
+
+	for hash, hasher := range hashers {
+		if matched, _ := hasher.Match("anything", hash); matched {
+			t.Errorf("%s matched", hash)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package gowebapi
+
+// Principal is the authenticated user, filled in by an Auther
+type Principal struct {
+	Subject string                 `json:"sub"`
+	Roles   []string               `json:"roles,omitempty"`
+	Scopes  []string               `json:"scopes,omitempty"`
+	Claims  map[string]interface{} `json:"claims,omitempty"`
+}
+
+func (self *Principal) HasRole(role string) bool {
+
+	return nil != self && contains(self.Roles, role)
+}
+
+func (self *Principal) HasScope(scope string) bool {
+
+	return nil != self && contains(self.Scopes, scope)
+}
+
+func contains(values []string, value string) bool {
+
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
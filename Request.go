@@ -2,12 +2,32 @@ package gowebapi
 
 import (
 	"net/http"
+	"net/url"
 )
 
 type Request struct {
 	Http *http.Request
-	*Route
-	Body     []byte
-	Data     map[string]interface{}
-	UserData string
+	*RouteMatch
+	Body      []byte
+	Data      map[string]interface{}
+	Form      url.Values
+	Files     []*File
+	UserData  string
+	Principal *Principal
+	formatter RequestFormatter
+	ticket    *ticket
+	// responseFormatter formats event stream data in the negotiated format
+	responseFormatter ResponseFormatter
+}
+
+// Decode decodes the request body into target using the request's formatter
+func (self *Request) Decode(target interface{}, strict bool) error {
+
+	decoder, isDecoder := self.formatter.(RequestDecoder)
+
+	if 0 == len(self.Body) || !isDecoder {
+		return nil
+	}
+
+	return decoder.DecodeRequest(self, target, strict)
 }
@@ -1,7 +1,13 @@
 package gowebapi
 
+import (
+	"io"
+	"net/http"
+	"os"
+)
+
 func NewResponse() Response {
-	return Response{0, "", nil, nil, make(map[string][]string, 0)}
+	return Response{0, "", nil, nil, make(map[string][]string, 0), nil, nil}
 }
 
 type Response struct {
@@ -10,4 +16,41 @@ type Response struct {
 	Body   []byte
 	Data   interface{}
 	Header map[string][]string
+	// Stream is copied to the client instead of formatting Data, and closed if it's an io.Closer
+	Stream io.Reader
+	// Writer writes the body to the client instead of formatting Data
+	Writer func(io.Writer) error
+}
+
+// SetCookie adds a Set-Cookie header for cookie
+func (self *Response) SetCookie(cookie *http.Cookie) {
+
+	if nil == self.Header {
+		self.Header = make(map[string][]string)
+	}
+
+	self.Header["Set-Cookie"] = append(self.Header["Set-Cookie"], cookie.String())
+}
+
+// contentLength is the length of the body, or -1 if it isn't known before it's written
+func (self *Response) contentLength() int64 {
+
+	switch stream := self.Stream.(type) {
+	case nil:
+		if nil != self.Writer {
+			return -1
+		}
+		return int64(len(self.Body))
+	case interface{ Len() int }:
+		// bytes.Reader, strings.Reader, bytes.Buffer
+		return int64(stream.Len())
+	case *os.File:
+		info, statErr := stream.Stat()
+		offset, seekErr := stream.Seek(0, io.SeekCurrent)
+		if nil == statErr && nil == seekErr && info.Mode().IsRegular() {
+			return info.Size() - offset
+		}
+	}
+
+	return -1
 }
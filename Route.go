@@ -1,17 +1,20 @@
 package gowebapi
 
 import (
+	"net/http"
 	"reflect"
 	"regexp"
+	"strconv"
 )
 
 type Route struct {
 	Path    *regexp.Regexp
 	Method  *regexp.Regexp
 	Headers map[string]*regexp.Regexp
-	Params  map[string]string
 	Target  interface{}
 	Action  string
+	// ParamNames holds the param name bound to each target argument ("" for non-scalar args)
+	ParamNames []string
 	Binder
 	Filter *Filter
 }
@@ -25,12 +28,16 @@ func (self *Route) ForMethod(method string) *Route {
 
 func (self *Route) ForHeader(name string, value string) *Route {
 
-	self.Headers[name] = regexp.MustCompile(value)
+	if nil == self.Headers {
+		self.Headers = make(map[string]*regexp.Regexp)
+	}
+
+	self.Headers[http.CanonicalHeaderKey(name)] = regexp.MustCompile(value)
 
 	return self
 }
 
-func (self *Route) ToFunc(target interface{}) *Route {
+func (self *Route) ToFunc(target interface{}, names ...string) *Route {
 
 	targetType := reflect.TypeOf(target)
 
@@ -44,11 +51,12 @@ func (self *Route) ToFunc(target interface{}) *Route {
 	}
 
 	self.Target = target
+	self.ParamNames = self.paramNames(targetType, names)
 
 	return self
 }
 
-func (self *Route) ToMethod(target interface{}, action string) *Route {
+func (self *Route) ToMethod(target interface{}, action string, names ...string) *Route {
 
 	targetType := reflect.TypeOf(target)
 
@@ -73,6 +81,14 @@ func (self *Route) ToMethod(target interface{}, action string) *Route {
 
 	self.Target = target
 	self.Action = action
+	self.ParamNames = self.paramNames(reflect.ValueOf(target).MethodByName(action).Type(), names)
+
+	return self
+}
+
+func (self *Route) WithBinder(binder Binder) *Route {
+
+	self.Binder = binder
 
 	return self
 }
@@ -83,3 +99,101 @@ func (self *Route) WithFilter(filter filterFunc) *Route {
 
 	return self
 }
+
+// RequireRoles, RequireScopes and RequirePolicy check the principal set by
+// an earlier WithFilter(auther.Authenticate)
+
+func (self *Route) RequireRoles(roles ...string) *Route {
+
+	return self.WithFilter(RolesFilter(roles...))
+}
+
+func (self *Route) RequireScopes(scopes ...string) *Route {
+
+	return self.WithFilter(ScopesFilter(scopes...))
+}
+
+func (self *Route) RequirePolicy(policy func(*Principal, *Request) bool) *Route {
+
+	return self.WithFilter(AuthorizeFilter(policy))
+}
+
+func (self *Route) paramNames(targetType reflect.Type, names []string) []string {
+
+	pathNames := make([]string, 0)
+	pathParams := map[string]bool{}
+
+	if nil != self.Path {
+		for _, name := range self.Path.SubexpNames()[1:] {
+			if "" != name {
+				pathNames = append(pathNames, name)
+				pathParams[name] = true
+			}
+		}
+	}
+
+	paramNames := make([]string, targetType.NumIn())
+	scalarNum := 0
+
+	for argNum := 0; argNum < targetType.NumIn(); argNum++ {
+
+		argType := targetType.In(argNum)
+
+		// files are named by their form field, never by path params
+		if isFileType(argType) && nil == names {
+			continue
+		}
+
+		if isStructType(argType) && !isFileType(argType) {
+
+			if "*gowebapi.Request" == argType.String() || "*gowebapi.Principal" == argType.String() || "*gowebapi.EventStream" == argType.String() {
+				continue
+			}
+
+			if reflect.Ptr == argType.Kind() {
+				argType = argType.Elem()
+			}
+
+			for fieldNum := 0; fieldNum < argType.NumField(); fieldNum++ {
+
+				pathName := argType.Field(fieldNum).Tag.Get("path")
+
+				if "" == pathName || "-" == pathName {
+					continue
+				}
+
+				if !pathParams[pathName] {
+					panic("Invalid path tag (route has no {" + pathName + "} param)")
+				}
+			}
+
+			continue
+		}
+
+		if nil != names {
+
+			if scalarNum >= len(names) {
+				panic("Invalid param names (expecting a name for each scalar argument)")
+			}
+
+			paramNames[argNum] = names[scalarNum]
+
+		} else if scalarNum < len(pathNames) {
+
+			paramNames[argNum] = pathNames[scalarNum]
+
+		} else if !isBodyType(argType) {
+
+			// an unnamed scalar has nothing to bind from, so it would always be zero
+			panic("Invalid route target (argument " + strconv.Itoa(argNum) + " has no path param to bind)")
+		}
+
+		scalarNum++
+	}
+
+	if nil != names && scalarNum != len(names) {
+		panic("Invalid param names (expecting a name for each scalar argument)")
+	}
+
+	return paramNames
+}
@@ -0,0 +1,6 @@
+package gowebapi
+
+type RouteMatch struct {
+	*Route
+	Params map[string]string
+}
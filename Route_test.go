@@ -0,0 +1,94 @@
+package gowebapi
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bindCheckController struct{}
+
+func (self *bindCheckController) Extra(id int64, count int) *Response {
+
+	return &Response{Status: 200}
+}
+
+func (self *bindCheckController) Partial(id int64) *Response {
+
+	return &Response{Status: 200}
+}
+
+func (self *bindCheckController) Raw(request *Request) *Response {
+
+	return &Response{Status: 200}
+}
+
+func TestUnbindableArgsPanicAtRegistration(t *testing.T) {
+
+	func() {
+
+		defer func() {
+			if recovered, _ := recover().(string); !strings.Contains(recovered, "argument 1 has no path param") {
+				t.Errorf("expected an unbindable argument panic, got %q", recovered)
+			}
+		}()
+
+		(&DefaultRouter{}).AddRoute("/items/{id}").ToMethod(&bindCheckController{}, "Extra")
+	}()
+
+	// targets may ignore path params
+	(&DefaultRouter{}).AddRoute("/items/{id}/{name}").ToMethod(&bindCheckController{}, "Partial")
+	(&DefaultRouter{}).AddRoute("/items/{id}/{name}").ToMethod(&bindCheckController{}, "Raw")
+
+	routes := (&DefaultRouter{}).AddRpcRoutes("/rpc/", &bindCheckController{})
+
+	if 1 != len(routes) || "Raw" != routes[0].Action {
+		t.Errorf("expected rpc routes to skip methods with scalar args, got %d routes", len(routes))
+	}
+}
+
+func TestHeaderConstrainedRoutes(t *testing.T) {
+
+	handler := NewDefaultHandler()
+	handler.Router().AddRoute("/items").ForHeader("Accept-Version", "^1$").ToFunc(func() *Response {
+		return &Response{Status: 200, Data: "v1"}
+	})
+	handler.Router().AddRoute("/items").ForHeader("Accept-Version", "^2$").ToFunc(func() *Response {
+		return &Response{Status: 200, Data: "v2"}
+	})
+
+	for version, body := range map[string]string{"1": `"v1"`, "2": `"v2"`} {
+
+		request := httptest.NewRequest("GET", "/items", nil)
+		request.Header.Set("accept-version", version)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if 200 != recorder.Code || body != recorder.Body.String() {
+			t.Errorf("version %s: expected 200 %s, got %d %s", version, body, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	for _, version := range []string{"3", ""} {
+
+		request := httptest.NewRequest("GET", "/items", nil)
+
+		if "" != version {
+			request.Header.Set("Accept-Version", version)
+		}
+
+		match, routeErr := handler.Router().Route(&Request{Http: request})
+
+		if nil != match || nil == routeErr || !strings.Contains(routeErr.Error(), "request headers don't satisfy route constraints") {
+			t.Errorf("version %q: expected a header constraint error, got %v", version, routeErr)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if 404 != recorder.Code {
+			t.Errorf("version %q: expected 404, got %d", version, recorder.Code)
+		}
+	}
+}
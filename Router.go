@@ -2,35 +2,40 @@ package gowebapi
 
 import (
 	"errors"
+	"net/http"
+	"reflect"
 	"regexp"
 )
 
 type Router interface {
-	Route(*Request) (*Route, error)
+	Route(*Request) (*RouteMatch, error)
 	AddRoute(string) *Route
-	AddRestRoutes(string, interface{})
+	AddRestRoutes(string, interface{}) Routes
+	AddRpcRoutes(string, interface{}) Routes
 }
 
 type DefaultRouter struct {
 	routes []*Route
 }
 
-func (self *DefaultRouter) Route(request *Request) (*Route, error) {
+func (self *DefaultRouter) Route(request *Request) (*RouteMatch, error) {
 
-	route := self.getRoute(request.Http.Method, request.Http.URL.Path)
+	route, routeError := self.getRoute(request.Http.Method, request.Http.URL.Path, request.Http.Header)
 
-	if nil == route {
-		return nil, errors.New("No matching routes")
+	if nil != routeError {
+		return nil, routeError
 	}
 
+	match := &RouteMatch{Route: route, Params: map[string]string{}}
+
 	params := route.Path.FindStringSubmatch(request.Http.URL.Path)
 	for i, param := range params {
 		if i > 0 && "" != param {
-			route.Params[route.Path.SubexpNames()[i]] = param
+			match.Params[route.Path.SubexpNames()[i]] = param
 		}
 	}
 
-	return route, nil
+	return match, nil
 }
 
 func (self *DefaultRouter) AddRoute(path string) *Route {
@@ -44,27 +49,85 @@ func (self *DefaultRouter) AddRoute(path string) *Route {
 	}
 
 	return self.addRoute(
-		&Route{Path: regexp.MustCompile(path), Params: map[string]string{}, Filter: &Filter{}},
+		&Route{Path: regexp.MustCompile(path), Headers: map[string]*regexp.Regexp{}, Filter: &Filter{}},
 	)
 }
 
-func (self *DefaultRouter) AddRestRoutes(path string, controller interface{}) {
-
-	self.AddRoute(path).
-		ForMethod("get").
-		ToMethod(controller, "Get")
-	self.AddRoute(path).
-		ForMethod("post").
-		ToMethod(controller, "Post")
-	self.AddRoute(path).
-		ForMethod("put").
-		ToMethod(controller, "Put")
-	self.AddRoute(path).
-		ForMethod("delete").
-		ToMethod(controller, "Delete")
+func (self *DefaultRouter) AddRestRoutes(path string, controller interface{}) Routes {
+
+	return Routes{
+		self.AddRoute(path).
+			ForMethod("get").
+			ToMethod(controller, "Get"),
+		self.AddRoute(path).
+			ForMethod("post").
+			ToMethod(controller, "Post"),
+		self.AddRoute(path).
+			ForMethod("put").
+			ToMethod(controller, "Put"),
+		self.AddRoute(path).
+			ForMethod("delete").
+			ToMethod(controller, "Delete"),
+	}
+}
+
+// AddRpcRoutes routes path+Name to each controller method returning *Response,
+// skipping methods with plain scalar args since rpc paths have no params to bind.
+// The routes accept any HTTP method, and don't inherit filters from other routes
+// to the same methods, so add any auth filters to the returned Routes too.
+func (self *DefaultRouter) AddRpcRoutes(path string, controller interface{}) Routes {
+
+	controllerType := reflect.TypeOf(controller)
+
+	if reflect.Ptr != controllerType.Kind() ||
+		reflect.Struct != controllerType.Elem().Kind() {
+
+		panic("Invalid controller type (expecting struct ptr)")
+	}
+
+	routes := Routes{}
+
+	for methodNum := 0; methodNum < controllerType.NumMethod(); methodNum++ {
+
+		method := controllerType.Method(methodNum)
+
+		if 1 != method.Type.NumOut() ||
+			"*gowebapi.Response" != method.Type.Out(0).String() {
+
+			continue
+		}
+
+		// rpc paths have no params, so plain scalar args could never be bound
+		if takesScalars(reflect.ValueOf(controller).Method(methodNum).Type()) {
+			continue
+		}
+
+		// anchor the path so /rpc/Get doesn't also match /rpc/GetAll
+		routes = append(routes, self.AddRoute("^"+path+method.Name+"$").
+			ToMethod(controller, method.Name))
+	}
+
+	return routes
+}
+
+// takesScalars is true for targets with plain scalar args, which bind from path params
+func takesScalars(targetType reflect.Type) bool {
+
+	for argNum := 0; argNum < targetType.NumIn(); argNum++ {
+
+		argType := targetType.In(argNum)
+
+		if !isStructType(argType) && !isFileType(argType) && !isBodyType(argType) {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (self *DefaultRouter) getRoute(method string, path string) *Route {
+func (self *DefaultRouter) getRoute(method string, path string, header http.Header) (*Route, error) {
+
+	routeError := errors.New("No matching routes")
 
 	for _, route := range self.routes {
 
@@ -72,12 +135,37 @@ func (self *DefaultRouter) getRoute(method string, path string) *Route {
 
 			if nil == route.Method || route.Method.MatchString(method) {
 
-				return route
+				if self.matchHeaders(route, header) {
+					return route, nil
+				}
+
+				routeError = errors.New("No matching routes (request headers don't satisfy route constraints)")
+			}
+		}
+	}
+
+	return nil, routeError
+}
+
+func (self *DefaultRouter) matchHeaders(route *Route, header http.Header) bool {
+
+	for name, value := range route.Headers {
+
+		matched := false
+
+		for _, headerValue := range header[name] {
+			if value.MatchString(headerValue) {
+				matched = true
+				break
 			}
 		}
+
+		if !matched {
+			return false
+		}
 	}
 
-	return nil
+	return true
 }
 
 func (self *DefaultRouter) addRoute(route *Route) *Route {
@@ -0,0 +1,46 @@
+package gowebapi
+
+// Routes applies the same settings to a group of routes, like those added by AddRestRoutes
+type Routes []*Route
+
+func (self Routes) ForHeader(name string, value string) Routes {
+
+	for _, route := range self {
+		route.ForHeader(name, value)
+	}
+
+	return self
+}
+
+func (self Routes) WithBinder(binder Binder) Routes {
+
+	for _, route := range self {
+		route.WithBinder(binder)
+	}
+
+	return self
+}
+
+func (self Routes) WithFilter(filter filterFunc) Routes {
+
+	for _, route := range self {
+		route.WithFilter(filter)
+	}
+
+	return self
+}
+
+func (self Routes) RequireRoles(roles ...string) Routes {
+
+	return self.WithFilter(RolesFilter(roles...))
+}
+
+func (self Routes) RequireScopes(scopes ...string) Routes {
+
+	return self.WithFilter(ScopesFilter(scopes...))
+}
+
+func (self Routes) RequirePolicy(policy func(*Principal, *Request) bool) Routes {
+
+	return self.WithFilter(AuthorizeFilter(policy))
+}
@@ -0,0 +1,160 @@
+package gowebapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SessionStore tracks revoked tickets until they expire, and users whose
+// earlier tickets are all revoked
+type SessionStore interface {
+	Revoke(ticketId string, expiry time.Time) error
+	RevokeUser(subject string, before time.Time) error
+	IsRevoked(ticketId string, subject string, issued time.Time) (bool, error)
+}
+
+type memorySessionStore struct {
+	mutex   sync.RWMutex
+	Tickets map[string]time.Time `json:"tickets"`
+	Users   map[string]time.Time `json:"users"`
+}
+
+func NewMemorySessionStore() SessionStore {
+
+	return newMemorySessionStore()
+}
+
+func newMemorySessionStore() *memorySessionStore {
+
+	return &memorySessionStore{
+		Tickets: map[string]time.Time{},
+		Users:   map[string]time.Time{},
+	}
+}
+
+func (self *memorySessionStore) Revoke(ticketId string, expiry time.Time) error {
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	// forget revoked tickets once they'd have expired anyway
+	now := time.Now()
+
+	for id, ticketExpiry := range self.Tickets {
+		if now.After(ticketExpiry) {
+			delete(self.Tickets, id)
+		}
+	}
+
+	self.Tickets[ticketId] = expiry
+
+	return nil
+}
+
+func (self *memorySessionStore) RevokeUser(subject string, before time.Time) error {
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if before.After(self.Users[subject]) {
+		self.Users[subject] = before
+	}
+
+	return nil
+}
+
+func (self *memorySessionStore) IsRevoked(ticketId string, subject string, issued time.Time) (bool, error) {
+
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+
+	if _, revoked := self.Tickets[ticketId]; revoked {
+		return true, nil
+	}
+
+	if before, revoked := self.Users[subject]; revoked && issued.Before(before) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// fileSessionStore keeps revocations in memory and saves them to a JSON file on every change
+type fileSessionStore struct {
+	*memorySessionStore
+	path      string
+	saveMutex sync.Mutex
+}
+
+func NewFileSessionStore(path string) (SessionStore, error) {
+
+	store := &fileSessionStore{memorySessionStore: newMemorySessionStore(), path: path}
+
+	file, readErr := ioutil.ReadFile(path)
+
+	if os.IsNotExist(readErr) {
+		return store, nil
+	}
+
+	if nil != readErr {
+		return nil, readErr
+	}
+
+	if unmarshalErr := json.Unmarshal(file, store.memorySessionStore); nil != unmarshalErr {
+		return nil, unmarshalErr
+	}
+
+	return store, nil
+}
+
+func (self *fileSessionStore) Revoke(ticketId string, expiry time.Time) error {
+
+	self.memorySessionStore.Revoke(ticketId, expiry)
+
+	return self.save()
+}
+
+func (self *fileSessionStore) RevokeUser(subject string, before time.Time) error {
+
+	self.memorySessionStore.RevokeUser(subject, before)
+
+	return self.save()
+}
+
+func (self *fileSessionStore) save() error {
+
+	self.saveMutex.Lock()
+	defer self.saveMutex.Unlock()
+
+	self.mutex.RLock()
+	file, marshalErr := json.Marshal(self.memorySessionStore)
+	self.mutex.RUnlock()
+
+	if nil != marshalErr {
+		return marshalErr
+	}
+
+	// write then rename so a crash never leaves a partial file
+	temp, tempErr := ioutil.TempFile(filepath.Dir(self.path), filepath.Base(self.path))
+
+	if nil != tempErr {
+		return tempErr
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, writeErr := temp.Write(file); nil != writeErr {
+		temp.Close()
+		return writeErr
+	}
+
+	if closeErr := temp.Close(); nil != closeErr {
+		return closeErr
+	}
+
+	return os.Rename(temp.Name(), self.path)
+}
@@ -0,0 +1,380 @@
+package gowebapi
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type ValidationRule func(value reflect.Value, param string) bool
+
+type FieldError struct {
+	Field string `json:"field" xml:"field"`
+	Rule  string `json:"rule" xml:"rule"`
+	Param string `json:"param,omitempty" xml:"param,omitempty"`
+}
+
+type ValidationError struct {
+	Errors []*FieldError `json:"errors" xml:"error"`
+}
+
+// RuleError reports a validate tag the Validator can't check, like an unknown rule
+// or a bad regex. It's a server misconfiguration rather than invalid data.
+type RuleError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+func (self *RuleError) Error() string {
+
+	return fmt.Sprintf("Invalid validation rule %s on %s: %s", self.Rule, self.Field, self.Message)
+}
+
+func (self *ValidationError) Error() string {
+
+	fields := make([]string, 0, len(self.Errors))
+
+	for _, fieldError := range self.Errors {
+		fields = append(fields, fieldError.Field+" ("+fieldError.Rule+")")
+	}
+
+	return "Validation failed: " + strings.Join(fields, ", ")
+}
+
+type Validator struct {
+	rules   map[string]ValidationRule
+	regexes sync.Map
+	// checked holds the types whose tags all name known rules
+	checked sync.Map
+}
+
+func NewValidator() *Validator {
+
+	validator := &Validator{rules: map[string]ValidationRule{}}
+
+	validator.AddRule("required", validateRequired)
+	validator.AddRule("min", validateMin)
+	validator.AddRule("max", validateMax)
+	validator.AddRule("email", validateEmail)
+	validator.AddRule("oneof", validateOneOf)
+	validator.AddRule("regex", validator.validateRegex)
+
+	return validator
+}
+
+func (self *Validator) AddRule(name string, rule ValidationRule) *Validator {
+
+	self.rules[name] = rule
+
+	return self
+}
+
+// Validate checks every field tagged validate:"rule,rule=param,..." and returns a
+// *ValidationError listing each failure. regex=... must be the last rule in a tag
+// since its pattern may contain commas. Tags naming rules this Validator doesn't
+// have, or bad regexes, return a *RuleError instead.
+func (self *Validator) Validate(data interface{}) (err error) {
+
+	if nil == data {
+		return nil
+	}
+
+	if ruleErr := self.checkTags(reflect.TypeOf(data)); nil != ruleErr {
+		return ruleErr
+	}
+
+	// values held in interfaces are only checked once they're found
+	defer func() {
+
+		if recovered := recover(); nil != recovered {
+
+			ruleErr, isRuleErr := recovered.(*RuleError)
+
+			if !isRuleErr {
+				panic(recovered)
+			}
+
+			err = ruleErr
+		}
+	}()
+
+	fieldErrors := self.validateValue(reflect.ValueOf(data), "", make([]*FieldError, 0))
+
+	if 0 < len(fieldErrors) {
+		return &ValidationError{fieldErrors}
+	}
+
+	return nil
+}
+
+func (self *Validator) validateValue(value reflect.Value, path string, fieldErrors []*FieldError) []*FieldError {
+
+	for reflect.Ptr == value.Kind() || reflect.Interface == value.Kind() {
+
+		if value.IsNil() {
+			return fieldErrors
+		}
+
+		if reflect.Interface == value.Kind() {
+			if ruleErr := self.checkTags(value.Elem().Type()); nil != ruleErr {
+				panic(ruleErr)
+			}
+		}
+
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		fieldErrors = self.validateStruct(value, path, fieldErrors)
+	case reflect.Slice, reflect.Array:
+		for index := 0; index < value.Len(); index++ {
+			fieldErrors = self.validateValue(value.Index(index), fmt.Sprintf("%s[%d]", path, index), fieldErrors)
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			fieldErrors = self.validateValue(value.MapIndex(key), fmt.Sprintf("%s[%v]", path, key), fieldErrors)
+		}
+	}
+
+	return fieldErrors
+}
+
+func (self *Validator) validateStruct(value reflect.Value, path string, fieldErrors []*FieldError) []*FieldError {
+
+	structType := value.Type()
+
+	for fieldNum := 0; fieldNum < structType.NumField(); fieldNum++ {
+
+		structField := structType.Field(fieldNum)
+
+		if "" != structField.PkgPath && !structField.Anonymous {
+			continue
+		}
+
+		field := value.Field(fieldNum)
+		fieldPath := path
+
+		if !structField.Anonymous {
+
+			fieldName := structField.Name
+			// use json tag field name if defined
+			if jsonName := strings.Split(structField.Tag.Get("json"), ",")[0]; "" != jsonName && "-" != jsonName {
+				fieldName = jsonName
+			}
+
+			if "" == fieldPath {
+				fieldPath = fieldName
+			} else {
+				fieldPath = fieldPath + "." + fieldName
+			}
+		}
+
+		fieldErrors = self.validateField(field, fieldPath, structField.Tag.Get("validate"), fieldErrors)
+		fieldErrors = self.validateValue(field, fieldPath, fieldErrors)
+	}
+
+	return fieldErrors
+}
+
+func (self *Validator) validateField(field reflect.Value, fieldPath string, tag string, fieldErrors []*FieldError) []*FieldError {
+
+	for _, rule := range validateRules(tag) {
+
+		name, param := rule[0], rule[1]
+		validationRule := self.rules[name]
+		value := field
+
+		// optional values are only checked when set
+		if reflect.Ptr == value.Kind() && "required" != name {
+
+			if value.IsNil() {
+				continue
+			}
+
+			value = value.Elem()
+		}
+
+		// report only the first failing rule for each field
+		if !validationRule(value, param) {
+			return append(fieldErrors, &FieldError{fieldPath, name, param})
+		}
+	}
+
+	return fieldErrors
+}
+
+// validateRules splits a validate tag into name and param pairs
+func validateRules(tag string) [][2]string {
+
+	rules := make([][2]string, 0)
+
+	for "" != tag {
+
+		var rule string
+
+		if strings.HasPrefix(tag, "regex=") {
+			rule, tag = tag, ""
+		} else if comma := strings.Index(tag, ","); -1 < comma {
+			rule, tag = tag[:comma], tag[comma+1:]
+		} else {
+			rule, tag = tag, ""
+		}
+
+		name, param := rule, ""
+
+		if equals := strings.Index(rule, "="); -1 < equals {
+			name, param = rule[:equals], rule[equals+1:]
+		}
+
+		rules = append(rules, [2]string{name, param})
+	}
+
+	return rules
+}
+
+// checkTags finds validate tags that can't be checked throughout a type, caching
+// the types that pass since rules are only ever added
+func (self *Validator) checkTags(valueType reflect.Type) error {
+
+	if _, checked := self.checked.Load(valueType); checked {
+		return nil
+	}
+
+	ruleErr := self.checkTypeTags(valueType, map[reflect.Type]bool{})
+
+	if nil == ruleErr {
+		self.checked.Store(valueType, true)
+	}
+
+	return ruleErr
+}
+
+func (self *Validator) checkTypeTags(valueType reflect.Type, visited map[reflect.Type]bool) error {
+
+	for reflect.Ptr == valueType.Kind() || reflect.Slice == valueType.Kind() || reflect.Array == valueType.Kind() || reflect.Map == valueType.Kind() {
+		valueType = valueType.Elem()
+	}
+
+	if reflect.Struct != valueType.Kind() || visited[valueType] {
+		return nil
+	}
+
+	visited[valueType] = true
+
+	for fieldNum := 0; fieldNum < valueType.NumField(); fieldNum++ {
+
+		structField := valueType.Field(fieldNum)
+
+		if "" != structField.PkgPath && !structField.Anonymous {
+			continue
+		}
+
+		for _, rule := range validateRules(structField.Tag.Get("validate")) {
+
+			field := valueType.Name() + "." + structField.Name
+
+			if _, exists := self.rules[rule[0]]; !exists {
+				return &RuleError{field, rule[0], "rule doesn't exist"}
+			}
+
+			if "regex" == rule[0] {
+				if _, compileErr := regexp.Compile(rule[1]); nil != compileErr {
+					return &RuleError{field, rule[0], compileErr.Error()}
+				}
+			}
+		}
+
+		if ruleErr := self.checkTypeTags(structField.Type, visited); nil != ruleErr {
+			return ruleErr
+		}
+	}
+
+	return nil
+}
+
+func (self *Validator) validateRegex(value reflect.Value, param string) bool {
+
+	regex, exists := self.regexes.Load(param)
+
+	if !exists {
+		regex, _ = self.regexes.LoadOrStore(param, regexp.MustCompile(param))
+	}
+
+	return reflect.String == value.Kind() && regex.(*regexp.Regexp).MatchString(value.String())
+}
+
+func validateRequired(value reflect.Value, param string) bool {
+
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return 0 < value.Len()
+	default:
+		return value.IsValid() && !value.IsZero()
+	}
+}
+
+func validateMin(value reflect.Value, param string) bool {
+
+	size, sized := validationSize(value)
+	limit, parseErr := strconv.ParseFloat(param, 64)
+
+	return sized && nil == parseErr && size >= limit
+}
+
+func validateMax(value reflect.Value, param string) bool {
+
+	size, sized := validationSize(value)
+	limit, parseErr := strconv.ParseFloat(param, 64)
+
+	return sized && nil == parseErr && size <= limit
+}
+
+func validateEmail(value reflect.Value, param string) bool {
+
+	if reflect.String != value.Kind() {
+		return false
+	}
+
+	address, parseErr := mail.ParseAddress(value.String())
+
+	return nil == parseErr && address.Address == value.String()
+}
+
+func validateOneOf(value reflect.Value, param string) bool {
+
+	text := fmt.Sprint(value)
+
+	for _, option := range strings.Fields(param) {
+		if option == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validationSize is the numeric value of numbers, or the length of strings and collections
+func validationSize(value reflect.Value) (float64, bool) {
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), true
+	}
+
+	return 0, false
+}
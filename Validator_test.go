@@ -0,0 +1,76 @@
+package gowebapi
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type unknownRuleModel struct {
+	Name string `validate:"requird"`
+}
+
+type badRegexModel struct {
+	Items []struct {
+		Sku string `validate:"regex=^[A-Z"`
+	}
+}
+
+type customRuleModel struct {
+	Sku string `validate:"sku"`
+}
+
+type tagCheckController struct{}
+
+func (self *tagCheckController) Unknown(model *unknownRuleModel) *Response {
+	return &Response{Status: 200}
+}
+func (self *tagCheckController) BadRegex(model badRegexModel) *Response {
+	return &Response{Status: 200}
+}
+func (self *tagCheckController) Custom(model *customRuleModel) *Response {
+	return &Response{Status: 200}
+}
+
+func TestBadValidateTagsFailRequests(t *testing.T) {
+
+	handler := NewDefaultHandler()
+	handler.Router().AddRoute("/unknown").ToMethod(&tagCheckController{}, "Unknown")
+	handler.Router().AddRoute("/regex").ToMethod(&tagCheckController{}, "BadRegex")
+
+	for _, path := range []string{"/unknown", "/regex"} {
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", path, strings.NewReader(`{}`)))
+
+		if 500 != recorder.Code {
+			t.Errorf("%s: expected 500, got %d", path, recorder.Code)
+		}
+	}
+}
+
+func TestCustomRulesBelongToTheirValidator(t *testing.T) {
+
+	validator := NewValidator()
+	handler := NewDefaultHandler()
+	handler.Router().AddRoute("/default").ToMethod(&tagCheckController{}, "Custom")
+	handler.Router().AddRoute("/custom").ToMethod(&tagCheckController{}, "Custom").WithBinder(&DefaultBinder{Validator: validator})
+
+	// rules added after the routes still count
+	validator.AddRule("sku", func(value reflect.Value, param string) bool {
+		return strings.HasPrefix(value.String(), "SKU-")
+	})
+
+	statuses := map[string]int{"/default": 500, "/custom": 422}
+
+	for path, status := range statuses {
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", path, strings.NewReader(`{"Sku":"123"}`)))
+
+		if status != recorder.Code {
+			t.Errorf("%s: expected %d, got %d", path, status, recorder.Code)
+		}
+	}
+}
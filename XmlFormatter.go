@@ -0,0 +1,370 @@
+package gowebapi
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// XmlFormatter decodes and encodes XML with encoding/xml, so xml struct tags apply.
+// Slices are wrapped in an <items> root with an element per item, named after the
+// item's struct type or <item>. Maps are wrapped in a <response> root with an
+// element per key.
+type XmlFormatter struct{}
+
+func (self *XmlFormatter) MimeType() string {
+
+	return "application/xml"
+}
+
+func (self *XmlFormatter) FormatRequest(request *Request) error {
+
+	requestBody, readErr := ioutil.ReadAll(request.Http.Body)
+
+	if nil != readErr {
+		return readErr
+	}
+
+	request.Body = requestBody
+
+	return nil
+}
+
+// DecodeRequest decodes the root element into target, whatever its name unless an
+// XMLName tag sets one. Slice and map targets are filled from the root's child
+// elements. encoding/xml ignores unknown elements and stops at mismatched values,
+// so strict makes no difference.
+func (self *XmlFormatter) DecodeRequest(request *Request, target interface{}, strict bool) error {
+
+	decoder := xml.NewDecoder(bytes.NewReader(request.Body))
+
+	for {
+
+		token, tokenErr := decoder.Token()
+
+		if io.EOF == tokenErr {
+			return &DataError{"", "no root element"}
+		}
+
+		if nil != tokenErr {
+			return &DataError{"", tokenErr.Error()}
+		}
+
+		if root, isStart := token.(xml.StartElement); isStart {
+
+			if decodeErr := decodeXmlElement(decoder, root, reflect.ValueOf(target).Elem()); nil != decodeErr {
+				return &DataError{"", decodeErr.Error()}
+			}
+
+			return nil
+		}
+	}
+}
+
+func (self *XmlFormatter) FormatResponse(response *Response) error {
+
+	if nil == response.Data {
+		response.Body = *new([]byte)
+		return nil
+	}
+
+	value := reflect.ValueOf(response.Data)
+	buffer := bytes.NewBufferString(xml.Header)
+	encoder := xml.NewEncoder(buffer)
+
+	if encodeErr := encodeXmlValue(encoder, xmlStart(xmlRootName(value)), value, false); nil != encodeErr {
+		return encodeErr
+	}
+
+	if flushErr := encoder.Flush(); nil != flushErr {
+		return flushErr
+	}
+
+	response.Body = buffer.Bytes()
+
+	return nil
+}
+
+func decodeXmlElement(decoder *xml.Decoder, start xml.StartElement, value reflect.Value) error {
+
+	for reflect.Ptr == value.Kind() {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		value = value.Elem()
+	}
+
+	// leave types that decode themselves to encoding/xml
+	if reflect.PtrTo(value.Type()).Implements(textUnmarshalerType) ||
+		reflect.PtrTo(value.Type()).Implements(reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()) {
+
+		return decoder.DecodeElement(value.Addr().Interface(), &start)
+	}
+
+	switch value.Kind() {
+	case reflect.Interface:
+
+		if 0 != value.NumMethod() {
+			break
+		}
+
+		generic, decodeErr := decodeXmlGeneric(decoder)
+
+		if nil != decodeErr {
+			return decodeErr
+		}
+
+		value.Set(reflect.ValueOf(generic))
+
+		return nil
+
+	case reflect.Map:
+
+		if reflect.String != value.Type().Key().Kind() {
+			break
+		}
+
+		if value.IsNil() {
+			value.Set(reflect.MakeMap(value.Type()))
+		}
+
+		return eachXmlChild(decoder, func(child xml.StartElement) error {
+
+			element := reflect.New(value.Type().Elem()).Elem()
+
+			if decodeErr := decodeXmlElement(decoder, child, element); nil != decodeErr {
+				return decodeErr
+			}
+
+			key := reflect.ValueOf(child.Name.Local).Convert(value.Type().Key())
+
+			// repeated elements collect into a slice when the map can hold one
+			if existing := value.MapIndex(key); existing.IsValid() && reflect.Interface == element.Kind() {
+				element = reflect.ValueOf(appendXmlValue(existing.Interface(), element.Interface()))
+			}
+
+			value.SetMapIndex(key, element)
+
+			return nil
+		})
+
+	case reflect.Slice:
+
+		if reflect.Uint8 == value.Type().Elem().Kind() {
+			break
+		}
+
+		return eachXmlChild(decoder, func(child xml.StartElement) error {
+
+			element := reflect.New(value.Type().Elem()).Elem()
+
+			if decodeErr := decodeXmlElement(decoder, child, element); nil != decodeErr {
+				return decodeErr
+			}
+
+			value.Set(reflect.Append(value, element))
+
+			return nil
+		})
+	}
+
+	return decoder.DecodeElement(value.Addr().Interface(), &start)
+}
+
+// decodeXmlGeneric decodes the rest of an element into a string, or a
+// map[string]interface{} if it has child elements
+func decodeXmlGeneric(decoder *xml.Decoder) (interface{}, error) {
+
+	var values map[string]interface{}
+	text := ""
+
+	for {
+
+		token, tokenErr := decoder.Token()
+
+		if nil != tokenErr {
+			return nil, tokenErr
+		}
+
+		switch token := token.(type) {
+		case xml.CharData:
+			text += string(token)
+		case xml.StartElement:
+
+			child, decodeErr := decodeXmlGeneric(decoder)
+
+			if nil != decodeErr {
+				return nil, decodeErr
+			}
+
+			if nil == values {
+				values = map[string]interface{}{}
+			}
+
+			if existing, exists := values[token.Name.Local]; exists {
+				child = appendXmlValue(existing, child)
+			}
+
+			values[token.Name.Local] = child
+
+		case xml.EndElement:
+
+			if nil != values {
+				return values, nil
+			}
+
+			return strings.TrimSpace(text), nil
+		}
+	}
+}
+
+func appendXmlValue(existing interface{}, value interface{}) []interface{} {
+
+	if values, isSlice := existing.([]interface{}); isSlice {
+		return append(values, value)
+	}
+
+	return []interface{}{existing, value}
+}
+
+// eachXmlChild calls decode for each child element, which must consume it
+func eachXmlChild(decoder *xml.Decoder, decode func(xml.StartElement) error) error {
+
+	for {
+
+		token, tokenErr := decoder.Token()
+
+		if nil != tokenErr {
+			return tokenErr
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if decodeErr := decode(token); nil != decodeErr {
+				return decodeErr
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// encodeXmlValue encodes value as a start element. Named struct types keep their
+// own element name, which xml tags may set, unless exact is true.
+func encodeXmlValue(encoder *xml.Encoder, start xml.StartElement, value reflect.Value, exact bool) error {
+
+	for (reflect.Ptr == value.Kind() || reflect.Interface == value.Kind()) && !value.IsNil() &&
+		!value.Type().Implements(reflect.TypeOf((*xml.Marshaler)(nil)).Elem()) &&
+		!value.Type().Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()) {
+
+		value = value.Elem()
+	}
+
+	if !value.IsValid() || ((reflect.Ptr == value.Kind() || reflect.Interface == value.Kind()) && value.IsNil()) {
+		return encoder.EncodeElement("", start)
+	}
+
+	switch value.Kind() {
+	case reflect.Map:
+
+		if reflect.String != value.Type().Key().Kind() {
+			return fmt.Errorf("Invalid response.Data type %T (xml map keys must be strings)", value.Interface())
+		}
+
+		keys := make([]string, 0, value.Len())
+
+		for _, key := range value.MapKeys() {
+			keys = append(keys, key.String())
+		}
+
+		sort.Strings(keys)
+
+		if startErr := encoder.EncodeToken(start); nil != startErr {
+			return startErr
+		}
+
+		for _, key := range keys {
+
+			element := xmlStart(key)
+
+			// keys that can't be element names become <item key="...">
+			if !isXmlName(key) {
+				element = xmlStart("item")
+				element.Attr = []xml.Attr{xml.Attr{Name: xml.Name{Local: "key"}, Value: key}}
+			}
+
+			if encodeErr := encodeXmlValue(encoder, element, value.MapIndex(reflect.ValueOf(key).Convert(value.Type().Key())), true); nil != encodeErr {
+				return encodeErr
+			}
+		}
+
+		return encoder.EncodeToken(start.End())
+
+	case reflect.Slice, reflect.Array:
+
+		if reflect.Uint8 == value.Type().Elem().Kind() {
+			break
+		}
+
+		if startErr := encoder.EncodeToken(start); nil != startErr {
+			return startErr
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			if encodeErr := encodeXmlValue(encoder, xmlStart("item"), value.Index(i), false); nil != encodeErr {
+				return encodeErr
+			}
+		}
+
+		return encoder.EncodeToken(start.End())
+
+	case reflect.Struct:
+
+		if !exact && "" != value.Type().Name() {
+			return encoder.Encode(value.Interface())
+		}
+	}
+
+	return encoder.EncodeElement(value.Interface(), start)
+}
+
+func xmlStart(name string) xml.StartElement {
+
+	return xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+// xmlRootName names the root of response.Data types that don't name themselves
+func xmlRootName(value reflect.Value) string {
+
+	for (reflect.Ptr == value.Kind() || reflect.Interface == value.Kind()) && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if (reflect.Slice == value.Kind() || reflect.Array == value.Kind()) && reflect.Uint8 != value.Type().Elem().Kind() {
+		return "items"
+	}
+
+	return "response"
+}
+
+func isXmlName(name string) bool {
+
+	if "" == name || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+
+	for i, char := range name {
+		if !unicode.IsLetter(char) && '_' != char && (0 == i || (!unicode.IsDigit(char) && '-' != char && '.' != char)) {
+			return false
+		}
+	}
+
+	return true
+}
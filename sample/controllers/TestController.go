@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"github.com/jkoreska/gowebapi"
+	"strconv"
+	"time"
 )
 
 type testController struct {
@@ -95,3 +97,21 @@ func (self *testController) Delete(id int64) *gowebapi.Response {
 		Status: 210,
 	}
 }
+
+func (self *testController) Clock(stream *gowebapi.EventStream) *gowebapi.Response {
+
+	go func() {
+
+		tick, _ := strconv.ParseInt(stream.LastEventId, 10, 64)
+
+		for {
+			tick++
+			if nil != stream.Send(&gowebapi.Event{Id: strconv.FormatInt(tick, 10), Data: time.Now()}) {
+				return
+			}
+			time.Sleep(time.Second)
+		}
+	}()
+
+	return stream.Response()
+}
@@ -13,6 +13,9 @@ func main() {
 	testController := controllers.NewTestController(auther)
 
 	handler := gowebapi.NewDefaultHandler()
+	handler.AddRequestFormatter("application/x-www-form-urlencoded", &gowebapi.FormFormatter{})
+	handler.AddRequestFormatter("multipart/form-data", &gowebapi.MultipartFormatter{})
+	handler.AddFormatter("application/xml", &gowebapi.XmlFormatter{}, &gowebapi.XmlFormatter{})
 
 	handler.Filter().
 		Add(gowebapi.CorsFilter).
@@ -24,15 +27,20 @@ func main() {
 		ToFunc(testController.Authenticate)
 
 	handler.Router().
-		AddRoute("/func/").
+		AddRoute("/func/{id}").
 		ToMethod(testController, "TestModel").
 		WithFilter(auther.Authenticate)
 
+	handler.Router().
+		AddRoute("/clock/").
+		ToMethod(testController, "Clock")
+
 	handler.Router().
 		AddRestRoutes("/rest/{id}/{test}", testController)
 
-	//handler.Router().
-	//	BindRpcRoutes("/rpc/", testController)
+	handler.Router().
+		AddRpcRoutes("/rpc/", testController).
+		WithFilter(auther.Authenticate)
 
 	fmt.Println("We're a GO!")
 